@@ -0,0 +1,152 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+type AccessFoo struct {
+	_ struct{} `crud:"table:afoo"`
+	Id int64 `crud:"a_id,pk"`
+	Name string `crud:"a_name"`
+	Created int64 `crud:"a_created,readonly"`
+	Owner string `crud:"a_owner,insertonly"`
+	Secret string `crud:"a_secret,writeonly"`
+}
+
+func TestAccessOptionsSQL(t *testing.T) {
+	f := AccessFoo{Id: 3, Name: "name", Created: 1, Owner: "owner", Secret: "secret"}
+
+	q, args, er := buildInsert(SQLite, NullStrict, "afoo", "a_id", f, nil)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `INSERT INTO "afoo" ("a_name", "a_owner", "a_secret") VALUES ($1, $2, $3)` ; q != expected {
+		t.Errorf("Insert mismatch\n%s\n%s", q, expected)
+	}
+
+	if expected := []interface{}{"name", "owner", "secret"} ; !reflect.DeepEqual(args, expected) {
+		t.Errorf("Insert args mismatch: %#v", args)
+	}
+
+	if q, _, er = buildUpdate(SQLite, NullStrict, "afoo", "a_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `UPDATE "afoo" SET "a_name" = $1, "a_secret" = $2 WHERE "a_id" = $3` ; q != expected {
+		t.Errorf("Update mismatch\n%s\n%s", q, expected)
+	}
+
+	if q, _, er = buildUpsert(SQLite, NullStrict, "afoo", []string{"a_id"}, f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `INSERT INTO "afoo" ("a_id", "a_name", "a_owner", "a_secret") VALUES ($1, $2, $3, $4) ON CONFLICT ("a_id") DO UPDATE SET "a_name" = excluded."a_name", "a_secret" = excluded."a_secret"` ; q != expected {
+		t.Errorf("Upsert mismatch\n%s\n%s", q, expected)
+	}
+
+	tm, _ := sqlToGoFields(reflect.TypeOf(f))
+
+	if q, expected := selectColumns(SQLite, tm), `"a_id", "a_name", "a_created", "a_owner"` ; q != expected {
+		t.Errorf("SELECT columns mismatch\n%s\n%s", q, expected)
+	}
+
+	for _, col := range []string{"a_created", "a_owner"} {
+		if _, _, er := buildUpdate(SQLite, NullStrict, "afoo", "a_id", f, col) ; er == nil {
+			t.Errorf("UpdateColumns of %s accepted", col)
+		}
+
+		if _, _, er := buildUpsert(SQLite, NullStrict, "afoo", []string{"a_id"}, f, OnConflictUpdate(col)) ; er == nil {
+			t.Errorf("OnConflictUpdate of %s accepted", col)
+		}
+	}
+
+	type BadAccess struct {
+		Col int64 `crud:"col,readonly,writeonly"`
+	}
+
+	type BadVersion struct {
+		Version int64 `crud:"version,version,insertonly"`
+	}
+
+	for _, ty := range []reflect.Type{reflect.TypeOf(BadAccess{}), reflect.TypeOf(BadVersion{})} {
+		if _, er := buildTypeMeta(ty) ; er == nil {
+			t.Errorf("%s accepted", ty)
+		}
+	}
+}
+
+func TestAccessOptions(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	_, er = db.Exec(`
+		CREATE TABLE afoo
+			( a_id INTEGER PRIMARY KEY
+			, a_name VARCHAR(32) NOT NULL
+			, a_created INTEGER NOT NULL DEFAULT 1338
+			, a_owner VARCHAR(32) NOT NULL
+			, a_secret VARCHAR(32) NOT NULL
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	f := AccessFoo{Name: "name", Created: 1, Owner: "owner", Secret: "secret"}
+
+	if er := InsertObj(db, &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	f.Name = "renamed"
+	f.Created = 2
+	f.Owner = "thief"
+	f.Secret = "changed"
+
+	if er := UpdateObj(db, f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var g AccessFoo
+
+	if er := Get(db, "afoo", "", f.Id, &g) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := (AccessFoo{Id: f.Id, Name: "renamed", Created: 1338, Owner: "owner"}) ; g != expected {
+		t.Errorf("Get mismatch\n%#v\n%#v", g, expected)
+	}
+
+	var secret string
+
+	if er := db.QueryRow("SELECT a_secret FROM afoo").Scan(&secret) ; er != nil || secret != "changed" {
+		t.Errorf("writeonly column not written: %q, %v", secret, er)
+	}
+
+	/* SELECT * still works, even strictly, without reading the writeonly column */
+	rows, er := db.Query("SELECT * FROM afoo")
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer rows.Close()
+
+	var h AccessFoo
+
+	if !rows.Next() {
+		t.Fatal(rows.Err())
+	}
+
+	if er := ScanStrict(rows, &h) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if h != g {
+		t.Errorf("Scan mismatch\n%#v\n%#v", h, g)
+	}
+}
@@ -0,0 +1,216 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+InsertAll creates a record for every element of slice (a slice of tagged
+structs or of pointers to them) using multi-row INSERT statements.
+
+Rows are split into as many statements as needed to stay under the Dialect's
+bind parameter limit (see Dialect.MaxParams) and row limit (see MaxRowsDialect). The statements aren't wrapped in
+a transaction; pass a *sql.Tx if the insert must be atomic.
+
+If sqlIdFieldName is non-empty, the generated ids are written back into the
+slice elements when they are integers and the Dialect allows it:
+ReturnLastInsertId dialects must implement BatchIdDialect, and Postgres scans
+them back with RETURNING, relying on PostgreSQL to return the rows of a
+multi-row INSERT in VALUES order. Other RETURNING dialects (such as
+WithReturning(SQLite), whose RETURNING order is documented as arbitrary) and
+MSSQL (whose OUTPUT order isn't guaranteed either) leave the ids untouched.
+
+Since every row of a statement has the same columns, "omitempty" is ignored.
+*/
+func InsertAll(db DbIsh, table, sqlIdFieldName string, slice interface{}) error {
+	return InsertAllContext(context.Background(), handleOf(db), table, sqlIdFieldName, slice)
+}
+
+/* InsertAllContext is the same as InsertAll, but passes ctx through to the database. */
+func InsertAllContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, slice interface{}) error {
+	h := handleOfContext(db)
+	d := h.dialect()
+
+	sliceVal := indirectV(reflect.ValueOf(slice))
+
+	if sliceVal.Kind() != reflect.Slice {
+		return opError(fmt.Errorf("Argument to crud.InsertAll is not a slice"), "insert", table, "")
+	}
+
+	if sliceVal.Len() == 0 {
+		return nil
+	}
+
+	tm, er := sqlToGoFields(sliceVal.Type().Elem())
+	if er != nil {
+		return opError(er, "insert", table, "")
+	}
+
+	for i := 0 ; i < sliceVal.Len() ; i += 1 {
+		if elem := sliceVal.Index(i) ; elem.Kind() == reflect.Ptr && elem.IsNil() {
+			return opError(fmt.Errorf("element %d of the slice is nil", i), "insert", table, "")
+		}
+	}
+
+	var idMeta *fieldMeta
+
+	if sqlIdFieldName != "" && (d.Returning() == ReturnLastInsertId || orderedReturning(d)) {
+		/* Only integer ids can be derived or scanned back generically */
+		if meta, ok := tm.lookup(sqlIdFieldName) ; ok && isIntKind(meta.Type.Kind()) {
+			idMeta = meta
+		}
+	}
+
+	for _, chunk := range insertChunks(d, tm, sqlIdFieldName, sliceVal) {
+		q, args := buildInsertAll(d, h.nullPolicy(), table, sqlIdFieldName, tm, chunk)
+
+		ids, er := execInsertAll(ctx, h, q, args, idMeta != nil, len(chunk))
+		if er != nil {
+			return opError(er, "insert", table, q)
+		}
+
+		for i, id := range ids {
+			setIntId(chunk[i].FieldByIndex(idMeta.Index), id)
+		}
+	}
+
+	return nil
+}
+
+/*
+insertChunks splits the elements of sliceVal into groups small enough that
+each multi-row INSERT stays within the Dialect's parameter and row limits.
+*/
+func insertChunks(d Dialect, tm *typeMeta, sqlIdFieldName string, sliceVal reflect.Value) [][]reflect.Value {
+	perRow := 0
+
+	for i := range tm.Fields {
+		if tm.Fields[i].inserted() && tm.Fields[i].SqlName != sqlIdFieldName {
+			perRow += 1
+		}
+	}
+
+	rowsPerChunk := sliceVal.Len()
+
+	if perRow > 0 && d.MaxParams() / perRow < rowsPerChunk {
+		rowsPerChunk = d.MaxParams() / perRow
+	}
+
+	if md, ok := d.(MaxRowsDialect) ; ok && md.MaxRows() < rowsPerChunk {
+		rowsPerChunk = md.MaxRows()
+	}
+
+	if rowsPerChunk < 1 {
+		rowsPerChunk = 1
+	}
+
+	chunks := [][]reflect.Value{}
+	chunk := make([]reflect.Value, 0, rowsPerChunk)
+
+	for i := 0 ; i < sliceVal.Len() ; i += 1 {
+		chunk = append(chunk, indirectV(sliceVal.Index(i)))
+
+		if len(chunk) == rowsPerChunk {
+			chunks = append(chunks, chunk)
+			chunk = make([]reflect.Value, 0, rowsPerChunk)
+		}
+	}
+
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+/* buildInsertAll generates a multi-row INSERT statement (and its arguments) for rows. */
+func buildInsertAll(d Dialect, nulls NullPolicy, table, sqlIdFieldName string, tm *typeMeta, rows []reflect.Value) (string, []interface{}) {
+	var sqlFields []string
+	args := []interface{}{}
+	groups := make([]string, len(rows))
+
+	for i, row := range rows {
+		var rowValues []interface{}
+		sqlFields, rowValues = insertColumns(row, tm, nulls, false, sqlIdFieldName)
+
+		groups[i] = "(" + placeholders(d, len(args) + 1, len(rowValues)) + ")"
+		args = append(args, rowValues...)
+	}
+
+	output, returning := "", ""
+
+	/* Ids returned in no particular order can't be matched to rows */
+	if sqlIdFieldName != "" && orderedReturning(d) {
+		output, returning = returningClauses(d, []string{sqlIdFieldName})
+	}
+
+	q := fmt.Sprintf("INSERT INTO %s (%s)%s VALUES %s%s", quoteName(d, table), quoteColumns(d, sqlFields), output, strings.Join(groups, ", "), returning)
+	return q, args
+}
+
+/*
+orderedReturning reports whether d returns the rows of a multi-row INSERT in
+VALUES order, so that RETURNING ids can be matched to rows by position. Only
+PostgreSQL does.
+*/
+func orderedReturning(d Dialect) bool {
+	return d == Postgres
+}
+
+/*
+execInsertAll runs a multi-row INSERT of n rows. If wantIds is set and the
+Dialect allows it, the generated ids are returned in row order.
+*/
+func execInsertAll(ctx context.Context, h *Handle, q string, args []interface{}, wantIds bool, n int) ([]int64, error) {
+	d := h.dialect()
+
+	if wantIds && d.Returning() != ReturnLastInsertId {
+		rows, er := h.QueryContext(ctx, q, args...)
+		if er != nil {
+			return nil, er
+		}
+		defer rows.Close()
+
+		ids := make([]int64, 0, n)
+
+		for rows.Next() {
+			var id int64
+
+			if er := rows.Scan(&id) ; er != nil {
+				return nil, er
+			}
+
+			ids = append(ids, id)
+		}
+
+		if er := rows.Err() ; er != nil {
+			return nil, er
+		}
+
+		if len(ids) != n {
+			return nil, fmt.Errorf("inserted %d rows but got %d ids back", n, len(ids))
+		}
+
+		return ids, rows.Close()
+	}
+
+	res, er := h.ExecContext(ctx, q, args...)
+	if er != nil {
+		return nil, er
+	}
+
+	batchDialect, ok := d.(BatchIdDialect)
+	if !wantIds || !ok {
+		return nil, nil
+	}
+
+	lastId, er := res.LastInsertId()
+	if er != nil {
+		return nil, er
+	}
+
+	return batchDialect.BatchIds(lastId, n), nil
+}
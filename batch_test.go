@@ -0,0 +1,115 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInsertAllGoldenSQL(t *testing.T) {
+	bars := []Bar{{Name: "a"}, {Name: "b"}}
+	tm, _ := sqlToGoFields(reflect.TypeOf(Bar{}))
+
+	expected := map[Dialect]string{
+		SQLite: `INSERT INTO "bar" ("bar_name") VALUES ($1), ($2)`,
+		Postgres: `INSERT INTO "bar" ("bar_name") VALUES ($1), ($2) RETURNING "bar_id"`,
+		MySQL: "INSERT INTO `bar` (`bar_name`) VALUES (?), (?)",
+		MSSQL: `INSERT INTO [bar] ([bar_name]) VALUES (@p1), (@p2)`,
+		/* SQLite's RETURNING order is arbitrary, so ids aren't asked for */
+		WithReturning(SQLite): `INSERT INTO "bar" ("bar_name") VALUES ($1), ($2)`,
+	}
+
+	for d, e := range expected {
+		chunks := insertChunks(d, tm, "bar_id", reflect.ValueOf(bars))
+		if len(chunks) != 1 {
+			t.Fatalf("Expected a single chunk, got %d", len(chunks))
+		}
+
+		q, args := buildInsertAll(d, NullStrict, "bar", "bar_id", tm, chunks[0])
+
+		if q != e || len(args) != 2 || args[0] != "a" || args[1] != "b" {
+			t.Errorf("InsertAll mismatch:\ne: %s\na: %s (%#v)", e, q, args)
+		}
+	}
+}
+
+func TestInsertAllChunking(t *testing.T) {
+	tm, _ := sqlToGoFields(reflect.TypeOf(Foo{}))
+	foos := make([]Foo, 1000)
+
+	/* 3 params per row => 333 rows per statement under SQLite's 999 */
+	chunks := insertChunks(SQLite, tm, "foo_id", reflect.ValueOf(foos))
+
+	if len(chunks) != 4 || len(chunks[0]) != 333 || len(chunks[3]) != 1 {
+		t.Errorf("Unexpected chunking: %d chunks", len(chunks))
+	}
+
+	/* 1 param per row => 2100 rows would fit MSSQL's params, but not its 1000 rows */
+	bars := make([]Bar, 2500)
+	tm, _ = sqlToGoFields(reflect.TypeOf(Bar{}))
+	chunks = insertChunks(MSSQL, tm, "bar_id", reflect.ValueOf(bars))
+
+	if len(chunks) != 3 || len(chunks[0]) != 1000 || len(chunks[2]) != 500 {
+		t.Errorf("Unexpected MSSQL chunking: %d chunks", len(chunks))
+	}
+}
+
+func TestInsertAll(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	foos := make([]Foo, 1200)
+
+	for i := range foos {
+		foos[i] = newFoo()
+		foos[i].Num = int64(i)
+	}
+
+	if er := InsertAll(db, "foo", "foo_id", foos) ; er != nil {
+		t.Fatal(er)
+	}
+
+	for i, f := range foos {
+		var f2 Foo
+
+		if f.Id == 0 {
+			t.Fatalf("InsertAll did not write back the id of element %d", i)
+		}
+
+		if er := Get(db, "foo", "foo_id", f.Id, &f2) ; er != nil {
+			t.Fatal(er)
+		}
+
+		if f2.Num != int64(i) {
+			t.Fatalf("Id %d written back to the wrong element (%d != %d)", f.Id, f2.Num, i)
+		}
+	}
+
+	ptrs := []*Foo{&Foo{Num: 1}, &Foo{Num: 2}}
+
+	if er := InsertAll(db, "foo", "foo_id", &ptrs) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if ptrs[0].Id == 0 || ptrs[1].Id != ptrs[0].Id + 1 {
+		t.Errorf("Unexpected ids written back through pointers: %d, %d", ptrs[0].Id, ptrs[1].Id)
+	}
+
+	if c := countFoos(t, db) ; c != 1202 {
+		t.Errorf("Expected 1202 foos, got %d", c)
+	}
+
+	if er := InsertAll(db, "foo", "foo_id", Foo{}) ; er == nil {
+		t.Errorf("Expected InsertAll to error on non-slice")
+	}
+
+	if er := InsertAll(db, "foo", "foo_id", []*Foo{&Foo{Num: 3}, nil}) ; er == nil {
+		t.Errorf("Expected InsertAll to error on a nil element")
+	}
+
+	if c := countFoos(t, db) ; c != 1202 {
+		t.Errorf("InsertAll with a nil element inserted rows: %d foos", c)
+	}
+}
@@ -0,0 +1,124 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+)
+
+func BenchmarkSqlToGoFields(b *testing.B) {
+	ty := reflect.TypeOf(OptionalFoo{})
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0 ; i < b.N ; i += 1 {
+			if _, er := buildTypeMeta(ty) ; er != nil {
+				b.Fatal(er)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0 ; i < b.N ; i += 1 {
+			if _, er := sqlToGoFields(ty) ; er != nil {
+				b.Fatal(er)
+			}
+		}
+	})
+}
+
+/*
+BenchmarkScanRow measures the per-row cost of Scan. The uncached variant
+evicts the type's metadata before every row, which is what Scan used to pay
+when it re-parsed the tags each time.
+*/
+func BenchmarkScanRow(b *testing.B) {
+	db, er := createDb()
+	if er != nil {
+		b.Fatal(er)
+	}
+	defer db.Close()
+
+	var Int64 int64 = 64
+	var String string = "string"
+
+	for i := 0 ; i < 100 ; i += 1 {
+		if _, er := Insert(db, "ofoo", "", OptionalFoo{Int64: &Int64, String: &String}) ; er != nil {
+			b.Fatal(er)
+		}
+	}
+
+	ty := reflect.TypeOf(OptionalFoo{})
+
+	bench := func(b *testing.B, evict bool) {
+		scanned := 0
+
+		for scanned < b.N {
+			rows, er := db.Query("SELECT * FROM ofoo")
+			if er != nil {
+				b.Fatal(er)
+			}
+
+			for rows.Next() && scanned < b.N {
+				if evict {
+					typeMetaCache.Delete(ty)
+				}
+
+				var f OptionalFoo
+
+				if er := Scan(rows, &f) ; er != nil {
+					b.Fatal(er)
+				}
+
+				scanned += 1
+			}
+
+			rows.Close()
+		}
+	}
+
+	b.Run("uncached", func(b *testing.B) { bench(b, true) })
+	b.Run("cached", func(b *testing.B) { bench(b, false) })
+}
+
+/*
+BenchmarkInsertUpdate measures repeated Inserts and Updates of the same type,
+sent as-is and through a StmtCache.
+*/
+func BenchmarkInsertUpdate(b *testing.B) {
+	db, er := createDb()
+	if er != nil {
+		b.Fatal(er)
+	}
+	defer db.Close()
+
+	cache := NewStmtCache(db, 16)
+	defer cache.Close()
+
+	bench := func(b *testing.B, db DbIsh) {
+		b.Run("insert", func(b *testing.B) {
+			for i := 0 ; i < b.N ; i += 1 {
+				if _, er := Insert(db, "foo", "foo_id", newFoo()) ; er != nil {
+					b.Fatal(er)
+				}
+			}
+		})
+
+		f := newFoo()
+
+		if f.Id, er = Insert(db, "foo", "foo_id", f) ; er != nil {
+			b.Fatal(er)
+		}
+
+		b.Run("update", func(b *testing.B) {
+			for i := 0 ; i < b.N ; i += 1 {
+				f.Num = int64(i)
+
+				if er := Update(db, "foo", "foo_id", f) ; er != nil {
+					b.Fatal(er)
+				}
+			}
+		})
+	}
+
+	b.Run("unprepared", func(b *testing.B) { bench(b, db) })
+	b.Run("cached", func(b *testing.B) { bench(b, cache) })
+}
@@ -1,6 +1,9 @@
 package crud
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+)
 
 /* 
 DbIsh provides an interface that is implemented by both sql.DB and sql.Tx. 
@@ -13,3 +16,57 @@ type DbIsh interface {
 	Prepare(string) (*sql.Stmt, error)
 	Query(string, ...interface{}) (*sql.Rows, error)
 }
+
+/*
+DbIshContext is the context-aware counterpart of DbIsh. It is implemented by
+sql.DB, sql.Tx and sql.Conn.
+
+The *Context variants of the crud functions accept DbIshContext's so that
+cancellation and deadlines propagate to the driver.
+*/
+type DbIshContext interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}
+
+/*
+contextDb returns db as a DbIshContext. DbIsh's that lack the *Context methods
+are wrapped so that the context is checked before each call but otherwise
+ignored.
+*/
+func contextDb(db DbIsh) DbIshContext {
+	if ctxDb, ok := db.(DbIshContext) ; ok {
+		return ctxDb
+	}
+
+	return noContextDb{db}
+}
+
+type noContextDb struct {
+	db DbIsh
+}
+
+func (n noContextDb) ExecContext(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+	if er := ctx.Err() ; er != nil {
+		return nil, er
+	}
+
+	return n.db.Exec(q, args...)
+}
+
+func (n noContextDb) PrepareContext(ctx context.Context, q string) (*sql.Stmt, error) {
+	if er := ctx.Err() ; er != nil {
+		return nil, er
+	}
+
+	return n.db.Prepare(q)
+}
+
+func (n noContextDb) QueryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	if er := ctx.Err() ; er != nil {
+		return nil, er
+	}
+
+	return n.db.Query(q, args...)
+}
@@ -0,0 +1,146 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+Delete removes the record corresponding to a tagged object from the database
+and returns the number of rows affected.
+
+As with Update, the record is identified by the column sqlIdFieldName, or by
+the fields tagged "pk" if it is empty (see also DeleteObj). If the object does
+not have its key set (or the value is 0), an error is returned.
+*/
+func Delete(db DbIsh, table, sqlIdFieldName string, arg interface{}) (int64, error) {
+	return DeleteContext(context.Background(), handleOf(db), table, sqlIdFieldName, arg)
+}
+
+/* DeleteContext is the same as Delete, but passes ctx through to the database. */
+func DeleteContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, arg interface{}) (int64, error) {
+	h := handleOfContext(db)
+
+	q, args, er := buildDelete(h.dialect(), table, sqlIdFieldName, arg)
+	if er != nil {
+		return 0, opError(er, "delete", table, "")
+	}
+
+	n, er := execAffected(ctx, h, q, args...)
+	return n, opError(er, "delete", table, q)
+}
+
+/*
+DeleteByID removes the record with the given primary key and returns the number
+of rows affected. Since there is no object to read "pk" tags from,
+sqlIdFieldName must be given.
+*/
+func DeleteByID(db DbIsh, table, sqlIdFieldName string, id interface{}) (int64, error) {
+	return DeleteByIDContext(context.Background(), handleOf(db), table, sqlIdFieldName, id)
+}
+
+/* DeleteByIDContext is the same as DeleteByID, but passes ctx through to the database. */
+func DeleteByIDContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, id interface{}) (int64, error) {
+	if sqlIdFieldName == "" {
+		return 0, opError(fmt.Errorf("no id column given"), "delete", table, "")
+	}
+
+	h := handleOfContext(db)
+	d := h.dialect()
+
+	q := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", quoteName(d, table), d.Quote(sqlIdFieldName), d.Placeholder(1))
+
+	n, er := execAffected(ctx, h, q, driverValue(id))
+	return n, opError(er, "delete", table, q)
+}
+
+/*
+DeleteWhere removes every record matching the example object and returns the
+number of rows affected.
+
+The WHERE clause is built from the example's tagged fields that are not set to
+their zero value, ANDed together. An example with no non-zero fields is an
+error rather than a request to empty the table.
+*/
+func DeleteWhere(db DbIsh, table string, example interface{}) (int64, error) {
+	return DeleteWhereContext(context.Background(), handleOf(db), table, example)
+}
+
+/* DeleteWhereContext is the same as DeleteWhere, but passes ctx through to the database. */
+func DeleteWhereContext(ctx context.Context, db DbIshContext, table string, example interface{}) (int64, error) {
+	h := handleOfContext(db)
+
+	q, args, er := buildDeleteWhere(h.dialect(), table, example)
+	if er != nil {
+		return 0, opError(er, "delete", table, "")
+	}
+
+	n, er := execAffected(ctx, h, q, args...)
+	return n, opError(er, "delete", table, q)
+}
+
+/* buildDelete generates the DELETE statement (and its arguments) for Delete. */
+func buildDelete(d Dialect, table, sqlIdFieldName string, arg interface{}) (string, []interface{}, error) {
+	val := indirectV(reflect.ValueOf(arg))
+
+	tm, er := sqlToGoFields(val.Type())
+	if er != nil {
+		return "", nil, er
+	}
+
+	keys, er := keyFields(tm, sqlIdFieldName)
+	if er != nil {
+		return "", nil, er
+	}
+
+	ids, er := keyValues(val, keys)
+	if er != nil {
+		return "", nil, er
+	}
+
+	q := fmt.Sprintf("DELETE FROM %s WHERE %s", quoteName(d, table), whereKey(d, keys, 1))
+	return q, ids, nil
+}
+
+/* buildDeleteWhere generates the DELETE statement (and its arguments) for DeleteWhere. */
+func buildDeleteWhere(d Dialect, table string, example interface{}) (string, []interface{}, error) {
+	val := indirectV(reflect.ValueOf(example))
+
+	tm, er := sqlToGoFields(val.Type())
+	if er != nil {
+		return "", nil, er
+	}
+
+	conds := []string{}
+	args := []interface{}{}
+
+	for i := range tm.Fields {
+		meta := &tm.Fields[i]
+
+		if val.FieldByIndex(meta.Index).IsZero() {
+			continue
+		}
+
+		args = append(args, fieldValue(val, meta))
+		conds = append(conds, fmt.Sprintf("%s = %s", d.Quote(meta.SqlName), d.Placeholder(len(args))))
+	}
+
+	if len(conds) == 0 {
+		return "", nil, fmt.Errorf("example %s has no non-zero fields, refusing to delete everything", tm.Type)
+	}
+
+	q := fmt.Sprintf("DELETE FROM %s WHERE %s", quoteName(d, table), strings.Join(conds, " AND "))
+	return q, args, nil
+}
+
+/* execAffected runs q and returns the number of rows it affected. */
+func execAffected(ctx context.Context, db DbIshContext, q string, args ...interface{}) (int64, error) {
+	res, er := db.ExecContext(ctx, q, args...)
+	if er != nil {
+		return 0, er
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,105 @@
+package crud
+
+import (
+	"testing"
+)
+
+func countFoos(t *testing.T, db DbIsh) int {
+	rows, er := db.Query("SELECT * FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []Foo{}
+
+	if er := ScanAll(rows, &foos) ; er != nil {
+		t.Fatal(er)
+	}
+
+	return len(foos)
+}
+
+func TestDelete(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := newFoo()
+
+	if _, er := Delete(db, "foo", "foo_id", f) ; er == nil {
+		t.Errorf("Expected Delete to error on zero-id field")
+	}
+
+	if _, er := Delete(db, "foo", "does_not_exist", f) ; er == nil {
+		t.Errorf("Expected Delete to error on non-existant ID field")
+	}
+
+	if f.Id, er = Insert(db, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if n, er := Delete(db, "foo", "foo_id", &f) ; er != nil {
+		t.Fatal(er)
+
+	} else if n != 1 {
+		t.Errorf("Expected Delete to affect 1 row, got %d", n)
+	}
+
+	if n, er := DeleteByID(db, "foo", "foo_id", f.Id) ; er != nil {
+		t.Fatal(er)
+
+	} else if n != 0 {
+		t.Errorf("Expected second delete to affect 0 rows, got %d", n)
+	}
+
+	if _, er := DeleteByID(db, "foo", "", f.Id) ; er == nil {
+		t.Errorf("Expected DeleteByID without an id column to fail")
+	}
+
+	if c := countFoos(t, db) ; c != 0 {
+		t.Errorf("Expected no foos left, got %d", c)
+	}
+}
+
+func TestDeleteWhere(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	for _, num := range []int64{1, 1, 2} {
+		f := newFoo()
+		f.Num = num
+
+		if _, er := Insert(db, "foo", "foo_id", f) ; er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if _, er := DeleteWhere(db, "foo", Foo{}) ; er == nil {
+		t.Errorf("Expected DeleteWhere to refuse an all-zero example")
+	}
+
+	q, args, er := buildDeleteWhere(Postgres, "foo", Foo{Num: 1, Str: "PANIC"})
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if e := `DELETE FROM "foo" WHERE "foo_num" = $1 AND "foo_str" = $2` ; q != e || len(args) != 2 {
+		t.Errorf("DeleteWhere mismatch:\ne: %s\na: %s (%#v)", e, q, args)
+	}
+
+	if n, er := DeleteWhere(db, "foo", Foo{Num: 1}) ; er != nil {
+		t.Fatal(er)
+
+	} else if n != 2 {
+		t.Errorf("Expected DeleteWhere to affect 2 rows, got %d", n)
+	}
+
+	if c := countFoos(t, db) ; c != 1 {
+		t.Errorf("Expected 1 foo left, got %d", c)
+	}
+}
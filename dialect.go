@@ -0,0 +1,268 @@
+package crud
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+Dialect describes the SQL syntax differences between database engines that
+crud has to account for when generating statements.
+
+crud ships with SQLite, Postgres, MySQL and MSSQL implementations. Package
+functions called with a plain DbIsh use DefaultDialect; wrap the DbIsh in a
+Handle (see NewHandle) to select a different one.
+*/
+type Dialect interface {
+	/* Placeholder returns the bind parameter for the nth argument (1-based). */
+	Placeholder(n int) string
+
+	/* Quote returns a single (unqualified) identifier quoted for use in SQL. */
+	Quote(ident string) string
+
+	/* Returning reports how generated values are fetched back after an INSERT. */
+	Returning() ReturningStyle
+
+	/*
+	OnConflict returns the clause appended to an INSERT to turn it into an
+	upsert. conflict names the columns of the unique constraint that may be
+	violated and update the columns to overwrite when it is; an empty update
+	means the existing row is left alone.
+	*/
+	OnConflict(conflict, update []string) (string, error)
+
+	/* MaxParams returns the maximum number of bind parameters per statement. */
+	MaxParams() int
+}
+
+/*
+BatchIdDialect is an optional interface for ReturnLastInsertId dialects that
+can work out the ids of every row of a multi-row INSERT from LastInsertId. It
+is used by InsertAll to write generated ids back.
+*/
+type BatchIdDialect interface {
+	BatchIds(lastInsertId int64, n int) []int64
+}
+
+/*
+MaxRowsDialect is an optional interface for dialects that limit the number of
+rows a single INSERT ... VALUES may list, on top of MaxParams. InsertAll splits
+its statements to stay under both.
+*/
+type MaxRowsDialect interface {
+	MaxRows() int
+}
+
+/*
+ReturningStyle enumerates the ways a database can hand back values generated
+by an INSERT (most commonly the primary key).
+*/
+type ReturningStyle int
+
+const (
+	/* ReturnLastInsertId uses sql.Result.LastInsertId. */
+	ReturnLastInsertId ReturningStyle = iota
+
+	/* ReturnClause appends a "RETURNING col, ..." clause to the statement. */
+	ReturnClause
+
+	/* ReturnOutput inserts an "OUTPUT INSERTED.col, ..." clause before VALUES. */
+	ReturnOutput
+)
+
+var (
+	/* SQLite uses $N placeholders, double-quoted identifiers and LastInsertId. */
+	SQLite Dialect = sqliteDialect{}
+
+	/* Postgres uses $N placeholders, double-quoted identifiers and RETURNING. */
+	Postgres Dialect = postgresDialect{}
+
+	/* MySQL uses ? placeholders, backquoted identifiers and LastInsertId. */
+	MySQL Dialect = mysqlDialect{}
+
+	/* MSSQL uses @pN placeholders, bracketed identifiers and OUTPUT INSERTED. */
+	MSSQL Dialect = mssqlDialect{}
+)
+
+/*
+DefaultDialect is used whenever a crud function is handed a DbIsh that isn't
+a Handle (or a Handle without a Dialect).
+*/
+var DefaultDialect Dialect = SQLite
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (sqliteDialect) Quote(ident string) string {
+	return `"` + strings.Replace(ident, `"`, `""`, -1) + `"`
+}
+
+func (sqliteDialect) Returning() ReturningStyle {
+	return ReturnLastInsertId
+}
+
+func (d sqliteDialect) OnConflict(conflict, update []string) (string, error) {
+	return onConflictClause(d, conflict, update)
+}
+
+/* SQLITE_MAX_VARIABLE_NUMBER defaulted to 999 before SQLite 3.32. */
+func (sqliteDialect) MaxParams() int {
+	return 999
+}
+
+/* SQLite reports the rowid of the last row; rowids are allocated consecutively. */
+func (sqliteDialect) BatchIds(lastInsertId int64, n int) []int64 {
+	return consecutiveIds(lastInsertId - int64(n) + 1, n)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgresDialect) Quote(ident string) string {
+	return `"` + strings.Replace(ident, `"`, `""`, -1) + `"`
+}
+
+func (postgresDialect) Returning() ReturningStyle {
+	return ReturnClause
+}
+
+func (d postgresDialect) OnConflict(conflict, update []string) (string, error) {
+	return onConflictClause(d, conflict, update)
+}
+
+func (postgresDialect) MaxParams() int {
+	return 65535
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) Quote(ident string) string {
+	return "`" + strings.Replace(ident, "`", "``", -1) + "`"
+}
+
+func (mysqlDialect) Returning() ReturningStyle {
+	return ReturnLastInsertId
+}
+
+/*
+MySQL always resolves conflicts against every unique key, so conflict is only
+used to pick a column for the no-op assignment that stands in for DO NOTHING.
+*/
+func (d mysqlDialect) OnConflict(conflict, update []string) (string, error) {
+	if len(update) == 0 {
+		if len(conflict) == 0 {
+			return "", fmt.Errorf("MySQL upserts need at least one conflict column")
+		}
+
+		col := d.Quote(conflict[0])
+		return "ON DUPLICATE KEY UPDATE " + col + " = " + col, nil
+	}
+
+	sets := make([]string, len(update))
+
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s = VALUES(%s)", d.Quote(col), d.Quote(col))
+	}
+
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "), nil
+}
+
+func (mysqlDialect) MaxParams() int {
+	return 65535
+}
+
+/* MySQL reports the id of the first row; the rest follow consecutively. */
+func (mysqlDialect) BatchIds(lastInsertId int64, n int) []int64 {
+	return consecutiveIds(lastInsertId, n)
+}
+
+type mssqlDialect struct{}
+
+func (mssqlDialect) Placeholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
+
+func (mssqlDialect) Quote(ident string) string {
+	return "[" + strings.Replace(ident, "]", "]]", -1) + "]"
+}
+
+func (mssqlDialect) Returning() ReturningStyle {
+	return ReturnOutput
+}
+
+func (mssqlDialect) OnConflict(conflict, update []string) (string, error) {
+	return "", fmt.Errorf("upserts need MERGE on MSSQL: %w", errors.ErrUnsupported)
+}
+
+func (mssqlDialect) MaxParams() int {
+	return 2100
+}
+
+/* A VALUES clause is limited to 1000 row constructors (error 10738). */
+func (mssqlDialect) MaxRows() int {
+	return 1000
+}
+
+/* consecutiveIds returns the n ids starting at first. */
+func consecutiveIds(first int64, n int) []int64 {
+	ids := make([]int64, n)
+
+	for i := range ids {
+		ids[i] = first + int64(i)
+	}
+
+	return ids
+}
+
+/*
+onConflictClause generates the "ON CONFLICT ... DO ..." clause shared by SQLite
+and Postgres.
+*/
+func onConflictClause(d Dialect, conflict, update []string) (string, error) {
+	target := ""
+
+	if len(conflict) > 0 {
+		target = " (" + quoteColumns(d, conflict) + ")"
+	}
+
+	if len(update) == 0 {
+		return "ON CONFLICT" + target + " DO NOTHING", nil
+	}
+
+	if target == "" {
+		return "", fmt.Errorf("ON CONFLICT DO UPDATE needs at least one conflict column")
+	}
+
+	sets := make([]string, len(update))
+
+	for i, col := range update {
+		sets[i] = fmt.Sprintf("%s = excluded.%s", d.Quote(col), d.Quote(col))
+	}
+
+	return "ON CONFLICT" + target + " DO UPDATE SET " + strings.Join(sets, ", "), nil
+}
+
+/*
+quoteName quotes a possibly schema-qualified name (e.g. "public.foo") by
+quoting each dot-separated piece individually.
+*/
+func quoteName(d Dialect, name string) string {
+	pieces := strings.Split(name, ".")
+
+	for i, piece := range pieces {
+		pieces[i] = d.Quote(piece)
+	}
+
+	return strings.Join(pieces, ".")
+}
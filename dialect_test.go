@@ -0,0 +1,138 @@
+package crud
+
+import (
+	"errors"
+	"testing"
+)
+
+type Bar struct {
+	Id int64 `crud:"bar_id"`
+	Name string `crud:"bar_name"`
+}
+
+type dialectGolden struct {
+	Dialect Dialect
+	Insert string
+	Update string
+}
+
+var dialectGoldens = map[string]dialectGolden{
+	"sqlite": {
+		Dialect: SQLite,
+		Insert: `INSERT INTO "bar" ("bar_name") VALUES ($1)`,
+		Update: `UPDATE "bar" SET "bar_name" = $1 WHERE "bar_id" = $2`,
+	},
+	"postgres": {
+		Dialect: Postgres,
+		Insert: `INSERT INTO "bar" ("bar_name") VALUES ($1) RETURNING "bar_id"`,
+		Update: `UPDATE "bar" SET "bar_name" = $1 WHERE "bar_id" = $2`,
+	},
+	"mysql": {
+		Dialect: MySQL,
+		Insert: "INSERT INTO `bar` (`bar_name`) VALUES (?)",
+		Update: "UPDATE `bar` SET `bar_name` = ? WHERE `bar_id` = ?",
+	},
+	"mssql": {
+		Dialect: MSSQL,
+		Insert: `INSERT INTO [bar] ([bar_name]) OUTPUT INSERTED.[bar_id] VALUES (@p1)`,
+		Update: `UPDATE [bar] SET [bar_name] = @p1 WHERE [bar_id] = @p2`,
+	},
+}
+
+func TestDialectGoldenSQL(t *testing.T) {
+	b := Bar{
+		Id: 7,
+		Name: "seven",
+	}
+
+	for name, golden := range dialectGoldens {
+		q, args, er := buildInsert(golden.Dialect, NullStrict, "bar", "bar_id", b, nil)
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		if q != golden.Insert {
+			t.Errorf("%s insert:\ne: %s\na: %s", name, golden.Insert, q)
+		}
+
+		if len(args) != 1 || args[0] != "seven" {
+			t.Errorf("%s insert args mismatch: %#v", name, args)
+		}
+
+		q, args, er = buildUpdate(golden.Dialect, NullStrict, "bar", "bar_id", b)
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		if q != golden.Update {
+			t.Errorf("%s update:\ne: %s\na: %s", name, golden.Update, q)
+		}
+
+		if len(args) != 2 || args[0] != "seven" || args[1] != int64(7) {
+			t.Errorf("%s update args mismatch: %#v", name, args)
+		}
+	}
+}
+
+func TestQuoteName(t *testing.T) {
+	if q := quoteName(Postgres, "public.foo") ; q != `"public"."foo"` {
+		t.Errorf("unexpected qualified name: %s", q)
+	}
+
+	if q := quoteName(MSSQL, "we]ird") ; q != "[we]]ird]" {
+		t.Errorf("unexpected escaped name: %s", q)
+	}
+}
+
+func TestHandleDialect(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	h := NewHandle(db, SQLite)
+
+	f := newFoo()
+
+	if f.Id, er = Insert(h, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Id == 0 {
+		t.Fatalf("Expected Insert through a Handle to return non-0 id")
+	}
+
+	f.Num = 99
+
+	if er := Update(h, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if NewHandle(h, MySQL).dialect() != MySQL || h.dialect() != SQLite {
+		t.Errorf("NewHandle did not copy the wrapped Handle")
+	}
+
+	if handleOf(db).dialect() != DefaultDialect {
+		t.Errorf("Plain DbIsh should use DefaultDialect")
+	}
+
+	/* A hand-built Handle fails cleanly until it is bound */
+	unbound := &Handle{Dialect: SQLite}
+
+	if _, er := Insert(unbound, "foo", "foo_id", f) ; !errors.Is(er, ErrUnbound) {
+		t.Errorf("Insert through an unbound Handle: %v", er)
+	}
+
+	if er := Get(unbound, "foo", "foo_id", f.Id, &f) ; !errors.Is(er, ErrUnbound) {
+		t.Errorf("Get through an unbound Handle: %v", er)
+	}
+
+	if _, er := unbound.Prepare("SELECT 1") ; !errors.Is(er, ErrUnbound) {
+		t.Errorf("Prepare through an unbound Handle: %v", er)
+	}
+
+	if er := Update(unbound.Bind(db), "foo", "foo_id", f) ; er != nil {
+		t.Errorf("Update through a bound Handle: %v", er)
+	}
+}
@@ -56,8 +56,85 @@ Each struct field that has a corresponding SQL row must be tagged with the SQL
 row name. For time types, the "unix" tag can be used to trigger marshalling between
 the Go time.Time type and a numeric SQL field. 
 
-Any pointer fields with a corresponding sql.Null* type are marshalled to/from 
-the Null type for proper interaction with database/sql.
+Untagged embedded structs are flattened into the embedding type, so shared
+groups of columns can be declared once. Named struct fields tagged with the
+"inline" option are flattened too, using their tag name as a column prefix:
+a field tagged `crud:"home_,inline"` maps its nested `crud:"city"` field to
+the home_city column. Pointers to structs are never flattened: embedding one
+with tagged fields, or tagging one "inline", is an error.
+
+The "pk" tag marks a field as part of the primary key. Functions that identify
+a record (Update, Delete, Get, ...) use the pk fields when no id column name is
+passed, which is how composite keys are supported. Keys may be of any type
+database/sql can bind, including strings, []byte and [16]byte.
+
+An integer field tagged "version" turns on optimistic locking: Update only
+succeeds if the stored version still matches, bumps it, and otherwise returns
+ErrStaleObject. Handles with RequireAffected set also make Update report a
+missing record (ErrNoRowsAffected) rather than silently doing nothing, and
+UpdateAffected returns the number of rows affected.
+
+Fields tagged "omitempty" are left out of Insert (so column DEFAULTs apply) and
+Update when they hold their zero value. UpdateColumns writes only the named
+columns, whatever their values, leaving the rest of the record untouched.
+Track snapshots an object as read, so that the Snapshot's Save later writes
+only the columns that changed since (and Changes lists them, e.g. for an audit
+log).
+
+Columns the database maintains itself (generated columns, DEFAULT now(),
+triggers, ...) can be tagged "readonly", so they are read but never written,
+or "insertonly", so they are written by Insert but never updated. Columns that
+shouldn't be read back (e.g. password hashes) can be tagged "writeonly", which
+leaves them out of the SELECTs crud generates and makes Scan skip them.
+
+Types that also declare their table name, either with a TableName() string
+method or a blank field tagged `crud:"table:foo"`, can be used with InsertObj,
+UpdateObj and DeleteObj, which need neither the table nor the id column.
+The generic Table[T] builds on the same metadata to provide a type-checked
+repository (Insert, Update, Delete, Get, List and Iter) for such types.
+
+Pointer fields (to any integer, float, bool or string kind, []byte, byte
+arrays or time.Time, including named types derived from them) are marshalled
+to/from the matching sql.Null* type for proper interaction with database/sql;
+scanning NULL sets them to nil. sql.Null[T] fields work as-is.
+
+Scanning NULL into any other field is an error by default. Fields tagged with
+the "nullzero" option instead get their zero value (which suits LEFT JOINs
+against NOT NULL columns); fields tagged "zeronull" also have zero values
+written back as NULL. A NullPolicy (package-wide via DefaultNullPolicy, or per
+Handle) does the same for every field.
+
+Columns without a matching field (and fields without a matching column) are
+ignored by Scan. ScanStrict, or a Handle with StrictScan set, reports them in
+a *ScanError instead, along with any duplicated column names.
+
+Fields whose type implements sql.Scanner or driver.Valuer (with either a value
+or a pointer receiver) are handed to database/sql untouched, and none of the
+conversions above (including "unix") are applied to them.
+
+Generated statements use SQLite syntax ($N placeholders, LastInsertId) unless
+told otherwise. To talk to a different database, wrap the connection in a
+Handle with the appropriate Dialect:
+
+	h := crud.NewHandle(db, crud.Postgres)
+	f.Id, er = crud.Insert(h, "foo", "foo_id", f)
+
+A Handle's Hooks are told about every statement (SQL, arguments, duration,
+rows affected and error) and scan run through it. SlogHook logs them with
+log/slog, and SlowQueryHook filters out the fast ones.
+
+Since a given type always generates the same SQL, wrapping the connection in a
+StmtCache (before handing it to NewHandle) saves the database from parsing each
+Insert, Update, ... statement more than once.
+
+Every function that talks to the database has a *Context variant (InsertContext,
+UpdateContext, ...) accepting a DbIshContext, which is satisfied by sql.DB,
+sql.Tx and sql.Conn, so cancellation and deadlines reach the driver.
+
+Errors are returned as a *Error, which records the operation, table, column,
+field and SQL involved and wraps the underlying cause, so errors.Is and
+errors.As work as expected (e.g. errors.Is(er, crud.ErrNotFound)). crud never
+prints anything.
 
 Despite the wonder, crud is not without drawbacks. As with all interfaces that
 use reflection internally, you lose both performance (untested as to how much)
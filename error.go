@@ -0,0 +1,148 @@
+package crud
+
+import (
+	"errors"
+	"strings"
+)
+
+/*
+Error is the type of every error returned by crud. It records what crud was
+doing when things went wrong, and wraps the underlying cause (a driver error,
+ctx.Err(), one of the sentinel errors below, ...), so errors.Is and errors.As
+see through it:
+
+	if er := crud.Get(db, "foo", "foo_id", id, &f) ; errors.Is(er, crud.ErrNotFound) {
+		...
+	}
+
+	var crudEr *crud.Error
+	if errors.As(er, &crudEr) {
+		log.Printf("%s failed: %s", crudEr.SQL, crudEr.Err)
+	}
+*/
+type Error struct {
+	/* Op is the operation that failed: "insert", "update", "scan", ... */
+	Op string
+
+	/* Table is the table being operated on, if any. */
+	Table string
+
+	/* Column is the column at fault, if a single one is. */
+	Column string
+
+	/* Field is the (possibly dotted) name of the Go field mapped to Column. */
+	Field string
+
+	/* SQL is the statement being run, if it got that far. */
+	SQL string
+
+	/* Err is the underlying error. */
+	Err error
+}
+
+var (
+	/* ErrNotFound is returned by Get and Reload when no record matches the id. */
+	ErrNotFound = errors.New("record not found")
+
+	/* ErrNoID is returned when a key field needed to identify a record is unset. */
+	ErrNoID = errors.New("key is 0 or not set")
+
+	/* ErrNotStruct is returned when a tagged struct was expected. */
+	ErrNotStruct = errors.New("not a struct")
+
+	/* ErrNotPointer is returned when an argument must be a pointer (to be written to). */
+	ErrNotPointer = errors.New("not a pointer")
+
+	/* ErrNoKey is returned when an operation needs fields tagged "pk" and there are none. */
+	ErrNoKey = errors.New("no fields tagged pk")
+
+	/* ErrNoTable is returned when a type used with InsertObj and friends declares no table name. */
+	ErrNoTable = errors.New("no table name (add a TableName method or a `crud:\"table:...\"` blank field)")
+
+	/* ErrUnknownColumn is returned when a column name doesn't match any tagged field. */
+	ErrUnknownColumn = errors.New("not a tagged field")
+
+	/*
+	ErrNoRowsAffected is returned by Update through a Handle with RequireAffected
+	set when no record matched the key.
+	*/
+	ErrNoRowsAffected = errors.New("no rows affected")
+
+	/*
+	ErrStaleObject is returned by Update when the object's version no longer
+	matches the stored record's (or the record is gone), meaning someone else
+	updated it first.
+	*/
+	ErrStaleObject = errors.New("stale object, record was modified or deleted")
+
+	/* ErrUnbound is returned by a Handle that has no database (see Handle.Bind). */
+	ErrUnbound = errors.New("handle is not bound to a database")
+)
+
+func (e *Error) Error() string {
+	msg := []string{"crud:"}
+
+	if e.Op != "" {
+		msg = append(msg, e.Op)
+	}
+
+	if e.Table != "" {
+		msg = append(msg, e.Table)
+	}
+
+	if e.Column != "" {
+		msg = append(msg, "column " + e.Column)
+	}
+
+	if e.Field != "" {
+		msg = append(msg, "(field " + e.Field + ")")
+	}
+
+	return strings.Join(msg, " ") + ": " + e.Err.Error()
+}
+
+/* Unwrap returns the underlying error, for errors.Is and errors.As. */
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+/*
+opError annotates er with the operation it came from. A *Error returned from
+deeper down (which may already know the column at fault) is copied, takes on
+op (the outermost operation is the one the caller asked for) and has its other
+blanks filled in; anything else is wrapped in a new one.
+*/
+func opError(er error, op, table, q string) error {
+	if er == nil {
+		return nil
+	}
+
+	e, ok := er.(*Error)
+
+	if ok {
+		cp := *e
+		e = &cp
+
+	} else {
+		e = &Error{Err: er}
+	}
+
+	if op != "" {
+		e.Op = op
+	}
+
+	if e.Table == "" {
+		e.Table = table
+	}
+
+	if e.SQL == "" {
+		e.SQL = q
+	}
+
+	return e
+}
+
+/* fieldError returns a *Error blaming the field described by meta. */
+func fieldError(meta *fieldMeta, er error) *Error {
+	return &Error{Column: meta.SqlName, Field: meta.GoName, Err: er}
+}
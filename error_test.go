@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	var crudEr *Error
+
+	var f Foo
+	er = Get(db, "foo", "foo_id", 1, &f)
+
+	if !errors.Is(er, ErrNotFound) || !errors.As(er, &crudEr) {
+		t.Fatalf("Get - unexpected error %v", er)
+	}
+
+	if crudEr.Op != "get" || crudEr.Table != "foo" || !strings.HasPrefix(crudEr.SQL, "SELECT ") {
+		t.Errorf("Get - missing details: %#v", crudEr)
+	}
+
+	er = Update(db, "foo", "foo_id", newFoo())
+
+	if !errors.Is(er, ErrNoID) || !errors.As(er, &crudEr) {
+		t.Fatalf("Update - unexpected error %v", er)
+	}
+
+	if crudEr.Op != "update" || crudEr.Column != "foo_id" || crudEr.Field != "Id" {
+		t.Errorf("Update - missing details: %#v", crudEr)
+	}
+
+	if er := Update(db, "foo", "nope", newFoo()) ; !errors.Is(er, ErrUnknownColumn) {
+		t.Errorf("Update - unexpected error %v", er)
+	}
+
+	if _, er := Insert(db, "foo", "foo_id", 42) ; !errors.Is(er, ErrNotStruct) {
+		t.Errorf("Insert - unexpected error %v", er)
+	}
+
+	if er := Get(db, "foo", "foo_id", 1, f) ; !errors.Is(er, ErrNotPointer) {
+		t.Errorf("Get - unexpected error %v", er)
+	}
+
+	/* Driver errors are wrapped along with the failing statement */
+	er = Update(db, "nope", "foo_id", Foo{Id: 1})
+
+	if !errors.As(er, &crudEr) || crudEr.Err == nil || !strings.Contains(crudEr.SQL, `"nope"`) {
+		t.Errorf("Update - unexpected error %v", er)
+	}
+
+	rows, er := db.Query("SELECT foo_id FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	ints := []int{}
+
+	if er := ScanAll(rows, &ints) ; !errors.As(er, &crudEr) || crudEr.Op != "scan" {
+		t.Errorf("ScanAll - unexpected error %v", er)
+	}
+
+	/* Scan failures within another operation are reported as that operation */
+	createZeroTable(t, db)
+
+	if _, er := db.Exec("INSERT INTO zfoo (z_id) VALUES (1)") ; er != nil {
+		t.Fatal(er)
+	}
+
+	var z ZeroFoo
+	er = Get(db, "zfoo", "z_id", 1, &z)
+
+	if !errors.As(er, &crudEr) || crudEr.Op != "get" || crudEr.Table != "zfoo" || crudEr.SQL == "" {
+		t.Errorf("Get - unexpected scan error %#v", er)
+	}
+
+	if _, er := Insert(db, "foo", "", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if rows, er = db.Query("SELECT foo_id, foo_num AS extra FROM foo") ; er != nil {
+		t.Fatal(er)
+	}
+	defer rows.Close()
+
+	rows.Next()
+
+	var id struct {
+		Id int64 `crud:"foo_id"`
+	}
+
+	er = ScanStrict(rows, &id)
+
+	if er == nil || strings.Count(er.Error(), "crud:") != 1 {
+		t.Errorf("ScanStrict - unexpected error %v", er)
+	}
+}
@@ -0,0 +1,118 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+/*
+Get loads the record with the given primary key into dest, which must be a
+pointer to a tagged struct.
+
+The SELECT lists exactly the columns named in dest's crud tags, except those
+tagged "writeonly". If there is no such record, ErrNotFound is returned and
+dest is left untouched.
+
+If sqlIdFieldName is empty, the fields tagged "pk" form the key; pass a Key
+holding one value per key field when there are several.
+*/
+func Get(db DbIsh, table, sqlIdFieldName string, id, dest interface{}) error {
+	return GetContext(context.Background(), handleOf(db), table, sqlIdFieldName, id, dest)
+}
+
+/* GetContext is the same as Get, but passes ctx through to the database. */
+func GetContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, id, dest interface{}) error {
+	h := handleOfContext(db)
+	d := h.dialect()
+
+	if reflect.ValueOf(dest).Kind() != reflect.Ptr {
+		return opError(fmt.Errorf("destination %T is %w", dest, ErrNotPointer), "get", table, "")
+	}
+
+	tm, er := sqlToGoFields(reflect.TypeOf(dest))
+	if er != nil {
+		return opError(er, "get", table, "")
+	}
+
+	keys, er := keyFields(tm, sqlIdFieldName)
+	if er != nil {
+		return opError(er, "get", table, "")
+	}
+
+	args, er := keyArgs(id, keys)
+	if er != nil {
+		return opError(er, "get", table, "")
+	}
+
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE %s", selectColumns(d, tm), quoteName(d, table), whereKey(d, keys, 1))
+
+	rows, er := h.QueryContext(ctx, q, args...)
+	if er != nil {
+		return opError(er, "get", table, q)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if er := rows.Err() ; er != nil {
+			return opError(er, "get", table, q)
+		}
+
+		return opError(ErrNotFound, "get", table, q)
+	}
+
+	if er := scan(ctx, rows, h.scanConfig(), dest) ; er != nil {
+		return opError(er, "get", table, q)
+	}
+
+	return opError(rows.Close(), "get", table, q)
+}
+
+/*
+Reload refreshes obj (a pointer to a tagged struct) in place from the record
+matching its primary key. As with Update, an unset (0) id is an error.
+*/
+func Reload(db DbIsh, table, sqlIdFieldName string, obj interface{}) error {
+	return ReloadContext(context.Background(), handleOf(db), table, sqlIdFieldName, obj)
+}
+
+/* ReloadContext is the same as Reload, but passes ctx through to the database. */
+func ReloadContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, obj interface{}) error {
+	val := reflect.ValueOf(obj)
+
+	if val.Kind() != reflect.Ptr {
+		return opError(fmt.Errorf("%T is %w", obj, ErrNotPointer), "reload", table, "")
+	}
+
+	val = indirectV(val)
+
+	tm, er := sqlToGoFields(val.Type())
+	if er != nil {
+		return opError(er, "reload", table, "")
+	}
+
+	keys, er := keyFields(tm, sqlIdFieldName)
+	if er != nil {
+		return opError(er, "reload", table, "")
+	}
+
+	ids, er := keyValues(val, keys)
+	if er != nil {
+		return opError(er, "reload", table, "")
+	}
+
+	return GetContext(ctx, db, table, sqlIdFieldName, Key(ids), obj)
+}
+
+/* selectColumns returns the quoted, comma-separated column list for tm, leaving out writeonly fields. */
+func selectColumns(d Dialect, tm *typeMeta) string {
+	cols := make([]string, len(tm.Fields))[:0]
+
+	for i := range tm.Fields {
+		if tm.Fields[i].selected() {
+			cols = append(cols, tm.Fields[i].SqlName)
+		}
+	}
+
+	return quoteColumns(d, cols)
+}
@@ -0,0 +1,76 @@
+package crud
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := newFoo()
+
+	if f.Id, er = Insert(db, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var f2 Foo
+
+	if er := Get(db, "foo", "foo_id", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2.Id != f.Id || f2.Num != f.Num || f2.Str != f.Str || !f2.Time.Equal(f.Time) {
+		t.Errorf("Get mismatch:\ne: %#v\na: %#v", f, f2)
+	}
+
+	if er := Get(db, "foo", "foo_id", f.Id + 1, &f2) ; !errors.Is(er, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", er)
+	}
+
+	if er := Get(db, "foo", "foo_id", f.Id, f2) ; er == nil {
+		t.Errorf("Expected Get to error on non-pointer destination")
+	}
+}
+
+func TestReload(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := newFoo()
+
+	if er := Reload(db, "foo", "foo_id", &f) ; er == nil {
+		t.Errorf("Expected Reload to error on zero-id field")
+	}
+
+	if f.Id, er = Insert(db, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := db.Exec("UPDATE foo SET foo_num = 1000 WHERE foo_id = $1", f.Id) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Reload(db, "foo", "foo_id", &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Num != 1000 {
+		t.Errorf("Reload did not refresh Num: %d", f.Num)
+	}
+
+	if _, er := Delete(db, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Reload(db, "foo", "foo_id", &f) ; !errors.Is(er, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", er)
+	}
+}
@@ -0,0 +1,209 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+)
+
+/*
+Handle binds a DbIsh to the settings crud uses when talking to it.
+
+A *Handle is itself a DbIsh, so it can be passed to every crud function in
+place of the database it wraps:
+
+	h := crud.NewHandle(db, crud.MySQL)
+	id, er := crud.Insert(h, "foo", "foo_id", f)
+
+Package functions handed a plain *sql.DB or *sql.Tx behave as if they were
+given a Handle using DefaultDialect.
+
+A Handle built by hand (&crud.Handle{...}) has no database until Bind is
+called; until then, everything run through it fails with ErrUnbound.
+*/
+type Handle struct {
+	db DbIshContext
+
+	/* Dialect used to generate SQL; DefaultDialect is used if nil. */
+	Dialect Dialect
+
+	/* NullPolicy used when scanning and writing; see DefaultNullPolicy. */
+	NullPolicy NullPolicy
+
+	/*
+	StrictScan makes every scan through the Handle fail with a *ScanError if
+	the columns and fields don't match up exactly (see ScanStrict).
+	*/
+	StrictScan bool
+
+	/* Hooks are told about every statement and scan run through the Handle. */
+	Hooks []Hook
+
+	/* RequireAffected makes Update fail with ErrNoRowsAffected if no record matched. */
+	RequireAffected bool
+}
+
+/*
+NewHandle wraps db using the given dialect.
+
+If db is already a *Handle, its settings are copied into the new Handle (which
+makes it cheap to override the dialect for a single call).
+*/
+func NewHandle(db DbIsh, dialect Dialect) *Handle {
+	if h, ok := db.(*Handle) ; ok {
+		cp := *h
+		cp.Dialect = dialect
+		return &cp
+	}
+
+	return &Handle{
+		db: contextDb(db),
+		Dialect: dialect,
+	}
+}
+
+/*
+NewHandleContext is the same as NewHandle, but accepts a DbIshContext (for
+example a *sql.Conn). The resulting Handle still implements DbIsh.
+*/
+func NewHandleContext(db DbIshContext, dialect Dialect) *Handle {
+	if h, ok := db.(*Handle) ; ok {
+		return NewHandle(h, dialect)
+	}
+
+	return &Handle{
+		db: db,
+		Dialect: dialect,
+	}
+}
+
+/*
+Bind returns a copy of the Handle with the same settings that talks to db
+instead. This is mostly useful for running with a Handle's settings inside a
+transaction:
+
+	tx, _ := db.Begin()
+	crud.Update(h.Bind(tx), "foo", "foo_id", f)
+*/
+func (h *Handle) Bind(db DbIsh) *Handle {
+	cp := *h
+	cp.db = contextDb(db)
+	return &cp
+}
+
+/* Exec implements DbIsh. */
+func (h *Handle) Exec(q string, args ...interface{}) (sql.Result, error) {
+	return h.ExecContext(context.Background(), q, args...)
+}
+
+/* Prepare implements DbIsh. */
+func (h *Handle) Prepare(q string) (*sql.Stmt, error) {
+	return h.PrepareContext(context.Background(), q)
+}
+
+/* Query implements DbIsh. */
+func (h *Handle) Query(q string, args ...interface{}) (*sql.Rows, error) {
+	return h.QueryContext(context.Background(), q, args...)
+}
+
+/* ExecContext implements DbIshContext. */
+func (h *Handle) ExecContext(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+	if h.db == nil {
+		return nil, ErrUnbound
+	}
+
+	if len(h.Hooks) == 0 {
+		return h.db.ExecContext(ctx, q, args...)
+	}
+
+	ctx, e := beforeExec(ctx, h.Hooks, q, args)
+	res, er := h.db.ExecContext(ctx, q, args...)
+
+	if er == nil {
+		if n, er := res.RowsAffected() ; er == nil {
+			e.RowsAffected = n
+		}
+	}
+
+	afterExec(ctx, h.Hooks, e, er)
+	return res, er
+}
+
+/* PrepareContext implements DbIshContext. */
+func (h *Handle) PrepareContext(ctx context.Context, q string) (*sql.Stmt, error) {
+	if h.db == nil {
+		return nil, ErrUnbound
+	}
+
+	return h.db.PrepareContext(ctx, q)
+}
+
+/* QueryContext implements DbIshContext. */
+func (h *Handle) QueryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	if h.db == nil {
+		return nil, ErrUnbound
+	}
+
+	if len(h.Hooks) == 0 {
+		return h.db.QueryContext(ctx, q, args...)
+	}
+
+	ctx, e := beforeExec(ctx, h.Hooks, q, args)
+	rows, er := h.db.QueryContext(ctx, q, args...)
+
+	afterExec(ctx, h.Hooks, e, er)
+	return rows, er
+}
+
+/*
+Scan is the same as crud.Scan, but handles NULLs according to the Handle's
+NullPolicy (and is strict if the Handle's StrictScan is set).
+*/
+func (h *Handle) Scan(rows *sql.Rows, args ...interface{}) error {
+	return scan(context.Background(), rows, h.scanConfig(), args...)
+}
+
+/* ScanAll is the same as crud.ScanAll, but uses the Handle's settings like Scan. */
+func (h *Handle) ScanAll(rows *sql.Rows, slicePtr interface{}) error {
+	return scanAll(context.Background(), rows, h.scanConfig(), slicePtr)
+}
+
+/* dialect returns the Dialect in use by the Handle. */
+func (h *Handle) dialect() Dialect {
+	if h.Dialect == nil {
+		return DefaultDialect
+	}
+
+	return h.Dialect
+}
+
+/* nullPolicy returns the NullPolicy in use by the Handle. */
+func (h *Handle) nullPolicy() NullPolicy {
+	if h.NullPolicy == NullDefault {
+		return DefaultNullPolicy
+	}
+
+	return h.NullPolicy
+}
+
+/* scanConfig returns the settings used when scanning through the Handle. */
+func (h *Handle) scanConfig() scanConfig {
+	return scanConfig{nulls: h.nullPolicy(), strict: h.StrictScan, hooks: h.Hooks}
+}
+
+/* handleOf returns db as a *Handle, wrapping it in a default one if needed. */
+func handleOf(db DbIsh) *Handle {
+	if h, ok := db.(*Handle) ; ok {
+		return h
+	}
+
+	return &Handle{db: contextDb(db)}
+}
+
+/* handleOfContext is handleOf for DbIshContext's. */
+func handleOfContext(db DbIshContext) *Handle {
+	if h, ok := db.(*Handle) ; ok {
+		return h
+	}
+
+	return &Handle{db: db}
+}
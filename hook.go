@@ -0,0 +1,189 @@
+package crud
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+/*
+Hook observes the statements and scans run through a Handle, which makes it
+the place to hang query logs, latency metrics and tracing:
+
+	h := crud.NewHandle(db, crud.Postgres)
+	h.Hooks = []crud.Hook{&crud.SlogHook{Logger: slog.Default()}}
+
+Hooks are called synchronously, in order (AfterExec in reverse order, so that
+the first hook's BeforeExec and AfterExec bracket everything else), and must
+not modify the events they are handed. Embed NopHook to implement only some of
+the methods.
+*/
+type Hook interface {
+	/*
+	BeforeExec is called before a statement is sent to the database. The
+	returned context is used for the statement and handed to AfterExec, so a
+	tracing hook can start a span here.
+	*/
+	BeforeExec(ctx context.Context, e *QueryEvent) context.Context
+
+	/* AfterExec is called once the database has answered. */
+	AfterExec(ctx context.Context, e *QueryEvent)
+
+	/* OnScan is called after each row is scanned through the Handle. */
+	OnScan(ctx context.Context, e *ScanEvent)
+}
+
+/*
+QueryEvent describes a statement run through a Handle. For queries, Duration
+only covers the time taken to get the first rows back.
+*/
+type QueryEvent struct {
+	SQL string
+	Args []interface{}
+
+	/* Start is when the statement was handed to the database. */
+	Start time.Time
+
+	/* Duration, RowsAffected and Err are only set for AfterExec. */
+	Duration time.Duration
+
+	/* RowsAffected is -1 for queries, and if the driver doesn't say. */
+	RowsAffected int64
+	Err error
+}
+
+/* ScanEvent describes a row scanned through a Handle. */
+type ScanEvent struct {
+	Columns []string
+	Duration time.Duration
+	Err error
+}
+
+/* NopHook implements Hook by doing nothing; embed it to implement only part of Hook. */
+type NopHook struct{}
+
+func (NopHook) BeforeExec(ctx context.Context, e *QueryEvent) context.Context {
+	return ctx
+}
+
+func (NopHook) AfterExec(ctx context.Context, e *QueryEvent) {}
+
+func (NopHook) OnScan(ctx context.Context, e *ScanEvent) {}
+
+/*
+SlogHook logs every statement (and failed scan) to Logger. Statements are
+logged at Level, and failures at slog.LevelError.
+
+Arguments are only logged if LogArgs is set, since they are likely to contain
+personal data or credentials.
+*/
+type SlogHook struct {
+	NopHook
+
+	/* Logger to write to; slog.Default() is used if nil. */
+	Logger *slog.Logger
+	Level slog.Level
+	LogArgs bool
+}
+
+func (l *SlogHook) AfterExec(ctx context.Context, e *QueryEvent) {
+	attrs := []slog.Attr{
+		slog.String("sql", e.SQL),
+		slog.Duration("duration", e.Duration),
+	}
+
+	if l.LogArgs {
+		attrs = append(attrs, slog.Any("args", e.Args))
+	}
+
+	if e.RowsAffected >= 0 {
+		attrs = append(attrs, slog.Int64("rows", e.RowsAffected))
+	}
+
+	level := l.Level
+
+	if e.Err != nil {
+		attrs = append(attrs, slog.Any("err", e.Err))
+		level = slog.LevelError
+	}
+
+	l.logger().LogAttrs(ctx, level, "crud: exec", attrs...)
+}
+
+func (l *SlogHook) OnScan(ctx context.Context, e *ScanEvent) {
+	if e.Err == nil {
+		return
+	}
+
+	l.logger().LogAttrs(ctx, slog.LevelError, "crud: scan", slog.Any("columns", e.Columns), slog.Any("err", e.Err))
+}
+
+/* logger returns the Logger in use by the SlogHook. */
+func (l *SlogHook) logger() *slog.Logger {
+	if l.Logger == nil {
+		return slog.Default()
+	}
+
+	return l.Logger
+}
+
+/*
+SlowQueryHook passes on only the statements and scans that took at least
+Threshold to Hook, e.g. to log slow queries:
+
+	h.Hooks = []crud.Hook{&crud.SlowQueryHook{
+		Threshold: 100 * time.Millisecond,
+		Hook: &crud.SlogHook{Level: slog.LevelWarn},
+	}}
+
+Since the duration isn't known in advance, Hook's BeforeExec is never called:
+it only sees the AfterExec (and OnScan) of slow statements, so that it is never
+left waiting for the end of a statement it was told about. This suits logging
+and metrics, but tracing hooks should be added to the Handle directly.
+*/
+type SlowQueryHook struct {
+	Threshold time.Duration
+	Hook Hook
+}
+
+func (s *SlowQueryHook) BeforeExec(ctx context.Context, e *QueryEvent) context.Context {
+	return ctx
+}
+
+func (s *SlowQueryHook) AfterExec(ctx context.Context, e *QueryEvent) {
+	if e.Duration >= s.Threshold {
+		s.Hook.AfterExec(ctx, e)
+	}
+}
+
+func (s *SlowQueryHook) OnScan(ctx context.Context, e *ScanEvent) {
+	if e.Duration >= s.Threshold {
+		s.Hook.OnScan(ctx, e)
+	}
+}
+
+/* beforeExec starts a QueryEvent for q and passes it to each of hooks. */
+func beforeExec(ctx context.Context, hooks []Hook, q string, args []interface{}) (context.Context, *QueryEvent) {
+	e := &QueryEvent{
+		SQL: q,
+		Args: args,
+		RowsAffected: -1,
+	}
+
+	for _, hook := range hooks {
+		ctx = hook.BeforeExec(ctx, e)
+	}
+
+	e.Start = time.Now()
+	return ctx, e
+}
+
+/* afterExec completes e with the outcome of the statement and passes it to each of hooks. */
+func afterExec(ctx context.Context, hooks []Hook, e *QueryEvent, er error) {
+	e.Duration = time.Since(e.Start)
+	e.Err = er
+
+	for i := len(hooks) - 1 ; i >= 0 ; i -= 1 {
+		hooks[i].AfterExec(ctx, e)
+	}
+}
@@ -0,0 +1,161 @@
+package crud
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+type recordHook struct {
+	before []string
+	after []QueryEvent
+	scans []ScanEvent
+	sawCtx bool
+}
+
+func (r *recordHook) BeforeExec(ctx context.Context, e *QueryEvent) context.Context {
+	r.before = append(r.before, e.SQL)
+	return context.WithValue(ctx, ctxKey{}, e.SQL)
+}
+
+func (r *recordHook) AfterExec(ctx context.Context, e *QueryEvent) {
+	r.sawCtx = ctx.Value(ctxKey{}) == e.SQL
+	r.after = append(r.after, *e)
+}
+
+func (r *recordHook) OnScan(ctx context.Context, e *ScanEvent) {
+	r.scans = append(r.scans, *e)
+}
+
+func TestHooks(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	rec := &recordHook{}
+	h := NewHandle(db, SQLite)
+	h.Hooks = []Hook{rec}
+
+	f := newFoo()
+
+	if f.Id, er = Insert(h, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if len(rec.before) != 1 || len(rec.after) != 1 || !strings.HasPrefix(rec.before[0], "INSERT INTO") {
+		t.Fatalf("Insert - hooks not called: %#v", rec)
+	}
+
+	if e := rec.after[0] ; e.RowsAffected != 1 || e.Err != nil || len(e.Args) != 3 || e.Duration <= 0 {
+		t.Errorf("Insert - unexpected event: %#v", e)
+	}
+
+	if !rec.sawCtx {
+		t.Errorf("AfterExec didn't get the context returned by BeforeExec")
+	}
+
+	var f2 Foo
+
+	if er := Get(h, "foo", "foo_id", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if e := rec.after[1] ; e.RowsAffected != -1 || !strings.HasPrefix(e.SQL, "SELECT") {
+		t.Errorf("Get - unexpected event: %#v", e)
+	}
+
+	if len(rec.scans) != 1 || len(rec.scans[0].Columns) != 4 || rec.scans[0].Err != nil {
+		t.Errorf("Get - unexpected scan events: %#v", rec.scans)
+	}
+
+	if er := Update(h, "nope", "foo_id", f) ; er == nil {
+		t.Fatal("Update of a missing table succeeded")
+	}
+
+	if e := rec.after[2] ; e.Err == nil {
+		t.Errorf("Update - error not reported: %#v", e)
+	}
+}
+
+func TestSlowQueryHook(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	rec := &recordHook{}
+	slow := &SlowQueryHook{Threshold: time.Hour, Hook: rec}
+
+	h := NewHandle(db, SQLite)
+	h.Hooks = []Hook{slow}
+
+	if _, er := Insert(h, "foo", "foo_id", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	/* The wrapped hook must never see a BeforeExec it won't see the end of */
+	if len(rec.before) != 0 || len(rec.after) != 0 {
+		t.Errorf("Fast query - hooks called: %#v", rec)
+	}
+
+	slow.Threshold = 0
+
+	if _, er := Insert(h, "foo", "foo_id", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if len(rec.before) != 0 || len(rec.after) != 1 || !strings.HasPrefix(rec.after[0].SQL, "INSERT INTO") {
+		t.Errorf("Slow query - unexpected calls: %#v", rec)
+	}
+}
+
+func TestSlogHook(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+
+	h := NewHandle(db, SQLite)
+	h.Hooks = []Hook{
+		&SlowQueryHook{
+			Threshold: time.Hour,
+			Hook: &SlogHook{Logger: logger, Level: slog.LevelWarn},
+		},
+	}
+
+	if _, er := Insert(h, "foo", "foo_id", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("SlowQueryHook - fast query logged: %s", buf)
+	}
+
+	h.Hooks = []Hook{&SlogHook{Logger: logger, Level: slog.LevelInfo}}
+
+	if _, er := Insert(h, "foo", "foo_id", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	out := buf.String()
+
+	if !strings.Contains(out, "level=INFO") || !strings.Contains(out, "INSERT INTO") || !strings.Contains(out, "rows=1") {
+		t.Errorf("SlogHook - unexpected output: %s", out)
+	}
+
+	if strings.Contains(out, "PANIC") {
+		t.Errorf("SlogHook - logged args without LogArgs: %s", out)
+	}
+}
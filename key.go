@@ -0,0 +1,170 @@
+package crud
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+Key holds the values of a composite primary key, in the order the key fields
+are declared, for use with Get:
+
+	crud.Get(db, "membership", "", crud.Key{userId, groupId}, &m)
+*/
+type Key []interface{}
+
+/*
+keyFields resolves the columns identifying a record of type tm. A non-empty
+sqlIdFieldName names a single key column; otherwise the fields tagged "pk" are
+used, which allows for composite keys.
+*/
+func keyFields(tm *typeMeta, sqlIdFieldName string) ([]*fieldMeta, error) {
+	if sqlIdFieldName != "" {
+		meta, ok := tm.lookup(sqlIdFieldName)
+		if !ok {
+			return nil, &Error{Column: sqlIdFieldName, Err: fmt.Errorf("%w of %s", ErrUnknownColumn, tm.Type)}
+		}
+
+		return []*fieldMeta{meta}, nil
+	}
+
+	if len(tm.PKs) == 0 {
+		return nil, fmt.Errorf("%s has %w and no id field was given", tm.Type, ErrNoKey)
+	}
+
+	return tm.keys(), nil
+}
+
+/*
+keyValues extracts the values of the key fields from val. A key field set to
+its zero value is an error (ErrNoID), since it can't identify a stored record.
+*/
+func keyValues(val reflect.Value, keys []*fieldMeta) ([]interface{}, error) {
+	values := make([]interface{}, len(keys))
+
+	for i, meta := range keys {
+		if isZero(val.FieldByIndex(meta.Index)) {
+			return nil, fieldError(meta, ErrNoID)
+		}
+
+		values[i] = fieldValue(val, meta)
+	}
+
+	return values, nil
+}
+
+/* keyArgs turns the id passed to Get into one argument per key column. */
+func keyArgs(id interface{}, keys []*fieldMeta) ([]interface{}, error) {
+	if key, ok := id.(Key) ; ok {
+		if len(key) != len(keys) {
+			return nil, fmt.Errorf("Key has %d values but the key has %d columns", len(key), len(keys))
+		}
+
+		args := make([]interface{}, len(key))
+
+		for i, v := range key {
+			args[i] = driverValue(v)
+		}
+
+		return args, nil
+	}
+
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("composite key with %d columns needs a crud.Key", len(keys))
+	}
+
+	return []interface{}{driverValue(id)}, nil
+}
+
+/* isKey reports whether meta is one of keys. */
+func isKey(meta *fieldMeta, keys []*fieldMeta) bool {
+	for _, key := range keys {
+		if key == meta {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* whereKey returns "k1 = $first AND k2 = $first+1 ..." for keys. */
+func whereKey(d Dialect, keys []*fieldMeta, first int) string {
+	conds := make([]string, len(keys))
+
+	for i, meta := range keys {
+		conds[i] = fmt.Sprintf("%s = %s", d.Quote(meta.SqlName), d.Placeholder(first + i))
+	}
+
+	return strings.Join(conds, " AND ")
+}
+
+/* isZero reports whether field is unset; empty byte slices count as unset. */
+func isZero(field reflect.Value) bool {
+	return field.IsZero() || (field.Kind() == reflect.Slice && field.Len() == 0)
+}
+
+/* isIntKind reports whether kind is one of the signed or unsigned integer kinds. */
+func isIntKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uint64
+}
+
+/*
+intId returns the value of an integer id field as an int64, or 0 for keys of
+any other type.
+*/
+func intId(field reflect.Value) int64 {
+	switch {
+	case field.CanInt():
+		return field.Int()
+
+	case field.CanUint():
+		return int64(field.Uint())
+	}
+
+	return 0
+}
+
+/* setIntId stores a generated integer id into an integer id field. */
+func setIntId(field reflect.Value, id int64) {
+	if field.CanInt() {
+		field.SetInt(id)
+
+	} else {
+		field.SetUint(uint64(id))
+	}
+}
+
+/*
+driverValue converts values database/sql can't bind by itself: fixed-size byte
+arrays (e.g. [16]byte UUIDs) are sent as []byte and named time types as
+time.Time, whether or not they are behind a pointer.
+*/
+func driverValue(v interface{}) interface{} {
+	if _, ok := v.(driver.Valuer) ; ok {
+		return v
+	}
+
+	val := reflect.ValueOf(v)
+
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return v
+		}
+
+		val = val.Elem()
+	}
+
+	switch {
+	case val.Kind() == reflect.Array && val.Type().Elem().Kind() == reflect.Uint8:
+		bytes := make([]byte, val.Len())
+		reflect.Copy(reflect.ValueOf(bytes), val)
+		return bytes
+
+	case val.Kind() == reflect.Struct && val.Type() != timeType && val.Type().ConvertibleTo(timeType):
+		return val.Convert(timeType).Interface()
+	}
+
+	return v
+}
@@ -0,0 +1,196 @@
+package crud
+
+import (
+	"testing"
+)
+
+type StringKeyFoo struct {
+	Code string `crud:"skey_code"`
+	Name string `crud:"skey_name"`
+}
+
+type UUIDFoo struct {
+	Id [16]byte `crud:"uuid_id"`
+	Name string `crud:"uuid_name"`
+}
+
+type Membership struct {
+	UserId int64 `crud:"user_id,pk"`
+	GroupId int64 `crud:"group_id,pk"`
+	Role string `crud:"role"`
+}
+
+func createKeyTables(t *testing.T, db DbIsh) {
+	for _, q := range []string{
+		`CREATE TABLE skey (skey_code VARCHAR(16) PRIMARY KEY, skey_name VARCHAR(32) NOT NULL)`,
+		`CREATE TABLE uuid (uuid_id BLOB PRIMARY KEY, uuid_name VARCHAR(32) NOT NULL)`,
+		`CREATE TABLE membership (user_id INTEGER NOT NULL, group_id INTEGER NOT NULL, role VARCHAR(16) NOT NULL, PRIMARY KEY (user_id, group_id))`,
+	} {
+		if _, er := db.Exec(q) ; er != nil {
+			t.Fatal(er)
+		}
+	}
+}
+
+func TestCompositeKeyGoldenSQL(t *testing.T) {
+	m := Membership{UserId: 1, GroupId: 2, Role: "admin"}
+
+	q, args, er := buildUpdate(Postgres, NullStrict, "membership", "", m)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if e := `UPDATE "membership" SET "role" = $1 WHERE "user_id" = $2 AND "group_id" = $3` ; q != e {
+		t.Errorf("update mismatch:\ne: %s\na: %s", e, q)
+	}
+
+	if len(args) != 3 || args[0] != "admin" || args[1] != int64(1) || args[2] != int64(2) {
+		t.Errorf("update args mismatch: %#v", args)
+	}
+
+	q, _, er = buildDelete(MSSQL, "membership", "", m)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if e := `DELETE FROM [membership] WHERE [user_id] = @p1 AND [group_id] = @p2` ; q != e {
+		t.Errorf("delete mismatch:\ne: %s\na: %s", e, q)
+	}
+
+	if _, _, er := buildUpdate(SQLite, NullStrict, "membership", "", Membership{UserId: 1}) ; er == nil {
+		t.Errorf("Expected Update to error on a partially-set composite key")
+	}
+
+	if _, _, er := buildUpdate(SQLite, NullStrict, "foo", "", newFoo()) ; er == nil {
+		t.Errorf("Expected Update to error without an id field or pk tags")
+	}
+}
+
+func TestStringKey(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createKeyTables(t, db)
+
+	f := StringKeyFoo{Code: "abc", Name: "first"}
+
+	if _, er := Insert(db, "skey", "", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	f.Name = "second"
+
+	if er := Update(db, "skey", "skey_code", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var f2 StringKeyFoo
+
+	if er := Get(db, "skey", "skey_code", "abc", &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2 != f {
+		t.Errorf("Get mismatch: %#v != %#v", f2, f)
+	}
+
+	if er := Update(db, "skey", "skey_code", StringKeyFoo{}) ; er == nil {
+		t.Errorf("Expected Update to error on empty string key")
+	}
+
+	if n, er := Delete(db, "skey", "skey_code", f) ; er != nil || n != 1 {
+		t.Errorf("Delete by string key failed: %d, %v", n, er)
+	}
+}
+
+func TestByteArrayKey(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createKeyTables(t, db)
+
+	f := UUIDFoo{Name: "uuid"}
+	copy(f.Id[:], "0123456789abcdef")
+
+	if _, er := Insert(db, "uuid", "", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	f.Name = "updated"
+
+	if er := Update(db, "uuid", "uuid_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var f2 UUIDFoo
+
+	if er := Get(db, "uuid", "uuid_id", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2 != f {
+		t.Errorf("Get mismatch: %#v != %#v", f2, f)
+	}
+
+	if n, er := DeleteByID(db, "uuid", "uuid_id", f.Id) ; er != nil || n != 1 {
+		t.Errorf("DeleteByID by [16]byte key failed: %d, %v", n, er)
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createKeyTables(t, db)
+
+	ms := []Membership{
+		{UserId: 1, GroupId: 1, Role: "member"},
+		{UserId: 1, GroupId: 2, Role: "member"},
+	}
+
+	if er := InsertAll(db, "membership", "", ms) ; er != nil {
+		t.Fatal(er)
+	}
+
+	ms[1].Role = "admin"
+
+	if er := Update(db, "membership", "", ms[1]) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var m Membership
+
+	if er := Get(db, "membership", "", Key{int64(1), int64(2)}, &m) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if m != ms[1] {
+		t.Errorf("Get mismatch: %#v != %#v", m, ms[1])
+	}
+
+	if er := Get(db, "membership", "", int64(1), &m) ; er == nil {
+		t.Errorf("Expected Get to require a Key for composite keys")
+	}
+
+	m = Membership{UserId: 1, GroupId: 1}
+
+	if er := Reload(db, "membership", "", &m) ; er != nil {
+		t.Fatal(er)
+
+	} else if m.Role != "member" {
+		t.Errorf("Reload mismatch: %#v", m)
+	}
+
+	if n, er := Delete(db, "membership", "", m) ; er != nil || n != 1 {
+		t.Errorf("Delete by composite key failed: %d, %v", n, er)
+	}
+}
@@ -4,55 +4,367 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"database/sql"
+	"database/sql/driver"
 )
 
-
 type fieldMeta struct {
 	GoName string
 	SqlName string
 	Unix bool
+	PK bool
+
+	/* NullZero and ZeroNull are set by the "nullzero" and "zeronull" tag options (see NullPolicy). */
+	NullZero bool
+	ZeroNull bool
+
+	/* Version marks the optimistic locking column (see Update). */
+	Version bool
+
+	/* OmitEmpty fields aren't written by Insert and Update when zero. */
+	OmitEmpty bool
+
+	/*
+	ReadOnly fields are read but never written (generated columns, server-side
+	defaults, ...), InsertOnly fields are written by Insert but never updated,
+	and WriteOnly fields are written but never read back.
+	*/
+	ReadOnly bool
+	InsertOnly bool
+	WriteOnly bool
+
+	/*
+	Index is the field's index sequence, for use with FieldByIndex; fields of
+	embedded and inline structs have more than one element.
+	*/
+	Index []int
+	Type reflect.Type
+
+	null nullKind
+	valuer valuerKind
+
+	/*
+	zeroable fields can stand in for NULL with their zero value: anything but
+	pointers, interfaces and sql.Scanner's, which all handle NULL themselves.
+	*/
+	zeroable bool
+}
+
+/* valuerKind describes how a field implements driver.Valuer, if at all. */
+type valuerKind int
+
+const (
+	valuerNone valuerKind = iota
+
+	/* valuerDirect fields can be passed to database/sql as-is. */
+	valuerDirect
+
+	/* valuerPtr fields only implement driver.Valuer through their address. */
+	valuerPtr
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+/*
+typeMeta is the compiled form of a struct type's crud tags. It is built once
+per type by sqlToGoFields and then shared (read-only) between goroutines.
+*/
+type typeMeta struct {
+	Type reflect.Type
+
+	/* Fields holds the tagged fields in declaration order. */
+	Fields []fieldMeta
+
+	/* PKs holds the indexes (into Fields) of the fields tagged "pk". */
+	PKs []int
+
+	/* Table is the table name declared by the type, if any. */
+	Table string
+
+	bySql map[string]int
+
+	/* version is the field tagged "version", if any. */
+	version *fieldMeta
 }
 
-/* 
+/* typeMetaCache maps reflect.Type to *typeMeta. */
+var typeMetaCache sync.Map
+
+/*
 sqlToGoFields extracts the data contained within the "crud" struct tags.
 
 The "crud" struct tags contain the name of the SQL column which stores the
-tagged field. sqlToGoFields returns a mapping from SQL column name to Go
-field name.
+tagged field. sqlToGoFields returns the tagged fields, in declaration order,
+along with a mapping from SQL column name to Go field. If two fields share a
+column name, the last one wins but keeps the position of the first.
+
+The result is cached per type, so the tags are only parsed the first time a
+type is seen. The returned typeMeta must not be modified.
 */
-func sqlToGoFields(ty reflect.Type) (map[string]fieldMeta, error) {
+func sqlToGoFields(ty reflect.Type) (*typeMeta, error) {
 	ty = indirectT(ty)
 
+	if cached, ok := typeMetaCache.Load(ty) ; ok {
+		return cached.(*typeMeta), nil
+	}
+
+	meta, er := buildTypeMeta(ty)
+	if er != nil {
+		return nil, er
+	}
+
+	cached, _ := typeMetaCache.LoadOrStore(ty, meta)
+	return cached.(*typeMeta), nil
+}
+
+/* buildTypeMeta does the (uncached) work of sqlToGoFields. */
+func buildTypeMeta(ty reflect.Type) (*typeMeta, error) {
 	if ty.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("sqlToGoFields: type %s is not a struct", ty.Name())
+		return nil, fmt.Errorf("%s is %w", ty, ErrNotStruct)
+	}
+
+	tm := &typeMeta{
+		Type: ty,
+		bySql: make(map[string]int),
+	}
+
+	if er := tm.addFields(ty, nil, "", "") ; er != nil {
+		return nil, er
+	}
+
+	for i := range tm.Fields {
+		meta := &tm.Fields[i]
+
+		if meta.PK {
+			tm.PKs = append(tm.PKs, i)
+		}
+
+		if meta.ReadOnly && (meta.InsertOnly || meta.WriteOnly) {
+			return nil, fieldError(meta, fmt.Errorf("readonly field of %s cannot also be insertonly or writeonly", ty))
+		}
+
+		if !meta.Version {
+			continue
+		}
+
+		if tm.version != nil {
+			return nil, fmt.Errorf("%s has more than one field tagged version", ty)
+		}
+
+		if !isIntKind(meta.Type.Kind()) || meta.PK {
+			return nil, fieldError(meta, fmt.Errorf("version field of %s must be an integer, and not part of the key", ty))
+		}
+
+		if !meta.updated() || meta.WriteOnly {
+			return nil, fieldError(meta, fmt.Errorf("version field of %s must be readable and updatable", ty))
+		}
+
+		tm.version = meta
+	}
+
+	if namer, ok := reflect.New(ty).Interface().(tableNamer) ; ok {
+		tm.Table = namer.TableName()
+	}
+
+	return tm, nil
+}
+
+/*
+tableNamer is implemented by types that declare their own table name. The
+method is called once, on a zero value, when the type is first seen.
+*/
+type tableNamer interface {
+	TableName() string
+}
+
+/*
+addFields adds the tagged fields of the struct type ty, which lives at index
+within tm.Type, to tm. Anonymous untagged struct fields are flattened into
+their parent, as are struct fields tagged "inline", whose tag name is then used
+as a prefix for the column names of the nested fields:
+
+	type Address struct {
+		Street string `crud:"street"`
+		City string `crud:"city"`
 	}
 
-	fieldMap := make(map[string]fieldMeta)
+	type Person struct {
+		Timestamps                  // created_at, updated_at
+		Home Address `crud:"home_,inline"`  // home_street, home_city
+	}
 
+Pointers to structs can't be flattened (there'd be nowhere to scan into while
+they're nil), so embedding one that has tagged fields, or tagging one "inline",
+is an error.
+*/
+func (tm *typeMeta) addFields(ty reflect.Type, index []int, sqlPrefix, goPrefix string) error {
 	for i := 0 ; i < ty.NumField() ; i += 1 {
 		field := ty.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
 
 		tag := field.Tag.Get("crud")
+		tagPieces := strings.Split(tag, ",")
+
+		if field.Name == "_" && strings.HasPrefix(tag, "table:") {
+			if index == nil {
+				tm.Table = strings.TrimPrefix(tag, "table:")
+			}
 
-		if tag != "" {
-			tagPieces := strings.Split(tag, ",")
+			continue
+		}
+
+		flatten := (field.Anonymous && tag == "") || hasTagOption(tagPieces, "inline")
 
-			meta := fieldMeta{
-				SqlName: tagPieces[0],
-				GoName: field.Name,
+		if field.Type.Kind() == reflect.Struct && flatten {
+			if er := tm.addFields(field.Type, fieldIndex, sqlPrefix + tagPieces[0], goPrefix + field.Name + ".") ; er != nil {
+				return er
 			}
 
-			for idx := 1; idx < len(tagPieces); idx += 1 {
-				if tagPieces[idx] == "unix" {
-					meta.Unix = true
-				}
+			continue
+		}
+
+		if ptr := field.Type ; ptr.Kind() == reflect.Ptr && ptr.Elem().Kind() == reflect.Struct && flatten && (tag != "" || hasTaggedFields(ptr.Elem())) {
+			return fmt.Errorf("%s%s of %s is a pointer to a struct, which cannot be flattened (embed %s instead)", goPrefix, field.Name, tm.Type, ptr.Elem())
+		}
+
+		if tag == "" {
+			continue
+		}
+
+		meta := fieldMeta{
+			SqlName: sqlPrefix + tagPieces[0],
+			GoName: goPrefix + field.Name,
+			Index: fieldIndex,
+			Type: field.Type,
+		}
+
+		for idx := 1; idx < len(tagPieces); idx += 1 {
+			switch tagPieces[idx] {
+			case "unix":
+				meta.Unix = true
+
+			case "pk":
+				meta.PK = true
+
+			case "nullzero":
+				meta.NullZero = true
+
+			case "zeronull":
+				meta.ZeroNull = true
+
+			case "version":
+				meta.Version = true
+
+			case "omitempty":
+				meta.OmitEmpty = true
+
+			case "readonly":
+				meta.ReadOnly = true
+
+			case "insertonly":
+				meta.InsertOnly = true
+
+			case "writeonly":
+				meta.WriteOnly = true
 			}
+		}
+
+		meta.null = nullKindOf(meta)
+		meta.valuer = valuerKindOf(meta.Type)
+		meta.zeroable = isZeroable(meta.Type)
+
+		if prev, ok := tm.bySql[meta.SqlName] ; ok {
+			tm.Fields[prev] = meta
+
+		} else {
+			tm.bySql[meta.SqlName] = len(tm.Fields)
+			tm.Fields = append(tm.Fields, meta)
+		}
+	}
+
+	return nil
+}
 
-			fieldMap[meta.SqlName] = meta
+/* hasTaggedFields reports whether any field of the struct type ty has a crud tag. */
+func hasTaggedFields(ty reflect.Type) bool {
+	for i := 0 ; i < ty.NumField() ; i += 1 {
+		if ty.Field(i).Tag.Get("crud") != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+/* hasTagOption reports whether the option opt follows the name in a split crud tag. */
+func hasTagOption(tagPieces []string, opt string) bool {
+	for _, piece := range tagPieces[1:] {
+		if piece == opt {
+			return true
 		}
 	}
 
-	return fieldMap, nil
+	return false
+}
+
+/* inserted reports whether the field is written by Insert (and Upsert). */
+func (meta *fieldMeta) inserted() bool {
+	return !meta.ReadOnly
+}
+
+/* updated reports whether the field is written by Update (and on Upsert conflicts). */
+func (meta *fieldMeta) updated() bool {
+	return !meta.ReadOnly && !meta.InsertOnly
+}
+
+/* selected reports whether the field is read back by Get, Scan and friends. */
+func (meta *fieldMeta) selected() bool {
+	return !meta.WriteOnly
+}
+
+/* implementsScanner reports whether a field of type ty can be scanned into directly. */
+func implementsScanner(ty reflect.Type) bool {
+	if reflect.PointerTo(ty).Implements(scannerType) {
+		return true
+	}
+
+	return ty.Kind() == reflect.Ptr && ty.Implements(scannerType)
+}
+
+/* isZeroable reports whether a field of type ty can represent NULL as its zero value. */
+func isZeroable(ty reflect.Type) bool {
+	if ty.Kind() == reflect.Ptr || ty.Kind() == reflect.Interface {
+		return false
+	}
+
+	return !implementsScanner(ty)
+}
+
+/* valuerKindOf reports how (if at all) a field of type ty implements driver.Valuer. */
+func valuerKindOf(ty reflect.Type) valuerKind {
+	if ty.Implements(valuerType) {
+		return valuerDirect
+	}
+
+	if reflect.PointerTo(ty).Implements(valuerType) {
+		return valuerPtr
+	}
+
+	return valuerNone
+}
+
+/* lookup returns the metadata for the field mapped to the SQL column sqlName. */
+func (tm *typeMeta) lookup(sqlName string) (*fieldMeta, bool) {
+	idx, ok := tm.bySql[sqlName]
+	if !ok {
+		return nil, false
+	}
+
+	return &tm.Fields[idx], true
 }
 
 /* indirectT returns the passed type, recursively indirected. */
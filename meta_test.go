@@ -0,0 +1,178 @@
+package crud
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTypeMetaCache(t *testing.T) {
+	ty := reflect.TypeOf(Foo{})
+
+	metas := make([]*typeMeta, 16)
+	wg := sync.WaitGroup{}
+
+	for i := range metas {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			tm, er := sqlToGoFields(ty)
+			if er != nil {
+				t.Error(er)
+			}
+
+			metas[i] = tm
+		}(i)
+	}
+
+	wg.Wait()
+
+	for _, tm := range metas {
+		if tm != metas[0] {
+			t.Fatalf("sqlToGoFields returned different metadata for the same type")
+		}
+	}
+
+	if tm, _ := sqlToGoFields(reflect.TypeOf(&Foo{})) ; tm != metas[0] {
+		t.Errorf("sqlToGoFields should share metadata between T and *T")
+	}
+
+	if _, er := sqlToGoFields(reflect.TypeOf("")) ; er == nil {
+		t.Errorf("Expected sqlToGoFields to error on non-struct type")
+	}
+
+	if meta, ok := metas[0].lookup("foo_num") ; !ok || meta.GoName != "Num" {
+		t.Errorf("lookup(foo_num) returned %#v", meta)
+	}
+}
+
+type Timestamps struct {
+	Created int64 `crud:"created_at"`
+	Updated int64 `crud:"updated_at"`
+}
+
+type Address struct {
+	Street string `crud:"street"`
+	City string `crud:"city"`
+}
+
+type Person struct {
+	_ struct{} `crud:"table:person"`
+	Id int64 `crud:"person_id,pk"`
+	Timestamps
+	Name string `crud:"name"`
+	Home Address `crud:"home_,inline"`
+	Work Address `crud:"work_,inline"`
+}
+
+func TestNestedFields(t *testing.T) {
+	tm, er := sqlToGoFields(reflect.TypeOf(Person{}))
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	expected := []string{"person_id", "created_at", "updated_at", "name", "home_street", "home_city", "work_street", "work_city"}
+
+	if len(tm.Fields) != len(expected) {
+		t.Fatalf("Expected %d fields, got %#v", len(expected), tm.Fields)
+	}
+
+	for i, col := range expected {
+		if tm.Fields[i].SqlName != col {
+			t.Errorf("Field %d: expected %s, got %s", i, col, tm.Fields[i].SqlName)
+		}
+	}
+
+	if meta, _ := tm.lookup("work_city") ; meta.GoName != "Work.City" {
+		t.Errorf("Unexpected GoName for work_city: %s", meta.GoName)
+	}
+}
+
+type EmbeddedPtrPerson struct {
+	Id int64 `crud:"person_id,pk"`
+	*Timestamps
+}
+
+type InlinePtrPerson struct {
+	Id int64 `crud:"person_id,pk"`
+	Home *Address `crud:"home_,inline"`
+}
+
+type UntaggedPtrPerson struct {
+	Id int64 `crud:"person_id,pk"`
+
+	/* Nothing here for crud, so it's none of its business */
+	*reflect.Method
+}
+
+func TestNestedPointers(t *testing.T) {
+	for _, v := range []interface{}{EmbeddedPtrPerson{}, InlinePtrPerson{}} {
+		if _, er := sqlToGoFields(reflect.TypeOf(v)) ; er == nil {
+			t.Errorf("Expected %T to be rejected", v)
+		}
+	}
+
+	if tm, er := sqlToGoFields(reflect.TypeOf(UntaggedPtrPerson{})) ; er != nil || len(tm.Fields) != 1 {
+		t.Errorf("Unexpected metadata for UntaggedPtrPerson: %v", er)
+	}
+}
+
+func TestNestedRoundTrip(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	_, er = db.Exec(`
+		CREATE TABLE person
+			( person_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, created_at INTEGER NOT NULL
+			, updated_at INTEGER NOT NULL
+			, name VARCHAR(32) NOT NULL
+			, home_street VARCHAR(32) NOT NULL
+			, home_city VARCHAR(32) NOT NULL
+			, work_street VARCHAR(32) NOT NULL
+			, work_city VARCHAR(32) NOT NULL
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	p := Person{
+		Timestamps: Timestamps{Created: 1, Updated: 2},
+		Name: "nested",
+		Home: Address{"1 Home St", "Hometown"},
+		Work: Address{"2 Work Ave", "Worktown"},
+	}
+
+	if er := InsertObj(db, &p) ; er != nil {
+		t.Fatal(er)
+	}
+
+	p.Updated = 3
+	p.Work.City = "Elsewhere"
+
+	if er := UpdateObj(db, p) ; er != nil {
+		t.Fatal(er)
+	}
+
+	rows, er := db.Query("SELECT * FROM person")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	people := []Person{}
+
+	if er := ScanAll(rows, &people) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if len(people) != 1 || people[0] != p {
+		t.Errorf("Round trip mismatch:\ne: %#v\na: %#v", p, people)
+	}
+}
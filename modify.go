@@ -1,109 +1,452 @@
 package crud
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"time"
 	"reflect"
 	"strings"
 )
 
-/* 
+/*
 Update syncs a tagged object with an existing record in the database.
- 
-The metadata contained in the crud tags don't include the table name or
-the name of the SQL primary ID, so they have to be passed in manually.
-If the object passed in as arg does not have a primary key set (or the
-value is 0), an error is returned.
+
+The record is identified by its key column, sqlIdFieldName, or by the fields
+tagged "pk" (see below); types that also declare their table name can use
+UpdateObj, which needs neither. If the object passed in as arg does not have
+its key set (or the value is 0), an error is returned.
+
+The primary key may be of any type (integers, strings, []byte, [16]byte, ...).
+If sqlIdFieldName is empty, the fields tagged "pk" form the key instead,
+which allows for composite keys:
+
+	type Membership struct {
+		UserId int64 `crud:"user_id,pk"`
+		GroupId int64 `crud:"group_id,pk"`
+		Role string `crud:"role"`
+	}
+
+	crud.Update(db, "membership", "", m)
+
+An integer field tagged "version" enables optimistic locking: the UPDATE only
+matches the record if its version is unchanged since obj was read, and bumps
+it by one. The new version is written back to arg (which must then be a
+pointer); if the record was modified (or deleted) in the meantime,
+ErrStaleObject is returned instead.
+
+Otherwise, updating a record that doesn't exist succeeds unless the Handle has
+RequireAffected set, in which case ErrNoRowsAffected is returned; UpdateAffected
+returns the number of rows affected instead. (With MySQL, this needs the
+clientFoundRows DSN option, since unchanged rows don't count as affected by
+default.)
+
+Fields tagged "omitempty" are left alone if they hold their zero value, and
+fields tagged "readonly" or "insertonly" always are.
 */
 func Update(db DbIsh, table, sqlIdFieldName string, arg interface{}) error {
-	val := indirectV(reflect.ValueOf(arg))
-	ty := val.Type()
+	return UpdateContext(context.Background(), handleOf(db), table, sqlIdFieldName, arg)
+}
 
-	fieldMap, er := sqlToGoFields(ty)
+/* UpdateContext is the same as Update, but passes ctx through to the database. */
+func UpdateContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, arg interface{}) error {
+	_, er := update(ctx, handleOfContext(db), table, sqlIdFieldName, arg, nil)
+	return er
+}
+
+/* UpdateAffected is the same as Update, but also returns the number of rows affected. */
+func UpdateAffected(db DbIsh, table, sqlIdFieldName string, arg interface{}) (int64, error) {
+	return UpdateAffectedContext(context.Background(), handleOf(db), table, sqlIdFieldName, arg)
+}
+
+/* UpdateAffectedContext is the same as UpdateAffected, but passes ctx through to the database. */
+func UpdateAffectedContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, arg interface{}) (int64, error) {
+	res, er := update(ctx, handleOfContext(db), table, sqlIdFieldName, arg, nil)
 	if er != nil {
-		return er
+		return 0, er
 	}
 
-	sqlFields := make([]string, len(fieldMap))[:0]
-	newValues := make([]interface{}, len(fieldMap))[:0]
-	placeholderId := 0
-	var id int64 = 0
+	n, er := res.RowsAffected()
+	return n, opError(er, "update", table, "")
+}
 
-	for sqlName, meta := range fieldMap {
-		goName := meta.GoName
+/*
+UpdateColumns is the same as Update, but only writes the named columns (even
+if they are tagged "omitempty" and zero), leaving the rest of the record as
+it is. This avoids clobbering concurrent changes to other columns, and suits
+PATCH-style updates:
 
-		if sqlName == sqlIdFieldName {
-			id = val.FieldByName(goName).Int()
+	crud.UpdateColumns(db, "foo", "foo_id", f, "foo_num", "foo_str")
 
-		} else {
-			fieldVal := val.FieldByName(goName).Interface()
+The columns must be tagged fields of arg, not part of the key, and neither
+readonly nor insertonly. A version field is always checked and bumped.
+*/
+func UpdateColumns(db DbIsh, table, sqlIdFieldName string, arg interface{}, cols ...string) error {
+	return UpdateColumnsContext(context.Background(), handleOf(db), table, sqlIdFieldName, arg, cols...)
+}
 
-			if timeVal, ok := fieldVal.(time.Time) ; ok && meta.Unix {
-				fieldVal = timeVal.Unix()
+/* UpdateColumnsContext is the same as UpdateColumns, but passes ctx through to the database. */
+func UpdateColumnsContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, arg interface{}, cols ...string) error {
+	if len(cols) == 0 {
+		return opError(fmt.Errorf("no columns to update"), "update", table, "")
+	}
 
-			} else if timeVal, ok := fieldVal.(*time.Time) ; ok && meta.Unix && timeVal != nil {
-				fieldVal = timeVal.Unix()
-			}
+	_, er := update(ctx, handleOfContext(db), table, sqlIdFieldName, arg, cols)
+	return er
+}
 
-			sqlFields = append(sqlFields, fmt.Sprintf("%s = $%d", sqlName, placeholderId))
-			newValues = append(newValues, fieldVal)
-			placeholderId += 1
-		}
+/* update does the work of UpdateContext and friends, returning the statement's result. */
+func update(ctx context.Context, h *Handle, table, sqlIdFieldName string, arg interface{}, cols []string) (sql.Result, error) {
+	q, newValues, er := buildUpdate(h.dialect(), h.nullPolicy(), table, sqlIdFieldName, arg, cols...)
+	if er != nil {
+		return nil, opError(er, "update", table, "")
 	}
 
-	if id == 0 {
-		return fmt.Errorf("%s is 0 or not set, cannot update", sqlIdFieldName)
+	res, er := h.ExecContext(ctx, q, newValues...)
+	if er != nil {
+		return nil, opError(er, "update", table, q)
 	}
 
-	newValues = append(newValues, id)
+	if er := checkUpdated(res, arg, h.RequireAffected) ; er != nil {
+		return nil, opError(er, "update", table, q)
+	}
 
-	q := fmt.Sprintf("UPDATE %s SET %s WHERE %s = $%d", table, strings.Join(sqlFields, ", "), sqlIdFieldName, placeholderId)
-	_, er = db.Exec(q, newValues...)
-	return er
+	return res, nil
+}
+
+/*
+checkUpdated checks that the UPDATE of arg affected a row, if arg is versioned
+or requireAffected is set, and bumps arg's version if it did.
+*/
+func checkUpdated(res sql.Result, arg interface{}, requireAffected bool) error {
+	val := indirectV(reflect.ValueOf(arg))
+	tm, _ := sqlToGoFields(val.Type())
+
+	if tm.version == nil && !requireAffected {
+		return nil
+	}
+
+	n, er := res.RowsAffected()
+	if er != nil {
+		return er
+	}
+
+	if n == 0 && tm.version != nil {
+		return fieldError(tm.version, ErrStaleObject)
+
+	} else if n == 0 {
+		return ErrNoRowsAffected
+	}
+
+	if tm.version != nil {
+		field := val.FieldByIndex(tm.version.Index)
+		setIntId(field, intId(field) + 1)
+	}
+
+	return nil
 }
 
-/* 
+/*
 Insert creates a new record in the datastore.
+
+Columns are listed in struct field declaration order, so a given type always
+produces the same SQL (which keeps statement and query-plan caches happy).
+
+If sqlIdFieldName is non-empty, the generated id is fetched back using the
+method appropriate to the Handle's Dialect (LastInsertId, RETURNING or OUTPUT)
+and returned. When the id comes back through RETURNING/OUTPUT and arg is a
+pointer, it is also written into arg's id field; this is the only way to get
+back generated ids that aren't integers (0 is returned for those). Dialects
+that don't support LastInsertId return 0 when no id field is given.
+
+Fields tagged "omitempty" are left out if they hold their zero value, so that
+the column's DEFAULT applies. Fields tagged "readonly" are always left out.
 */
 func Insert(db DbIsh, table, sqlIdFieldName string, arg interface{}) (int64, error) {
+	return InsertContext(context.Background(), handleOf(db), table, sqlIdFieldName, arg)
+}
+
+/* InsertContext is the same as Insert, but passes ctx through to the database. */
+func InsertContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, arg interface{}) (int64, error) {
+	h := handleOfContext(db)
+	d := h.dialect()
+
+	if d.Returning() != ReturnLastInsertId && sqlIdFieldName != "" {
+		return insertReturning(ctx, h, table, sqlIdFieldName, arg, nil)
+	}
+
+	q, newValues, er := buildInsert(d, h.nullPolicy(), table, sqlIdFieldName, arg, nil)
+	if er != nil {
+		return 0, opError(er, "insert", table, "")
+	}
+
+	res, er := h.ExecContext(ctx, q, newValues...)
+	if er != nil {
+		return 0, opError(er, "insert", table, q)
+	}
+
+	if d.Returning() != ReturnLastInsertId {
+		return 0, nil
+	}
+
+	id, er := res.LastInsertId()
+	return id, opError(er, "insert", table, q)
+}
+
+/*
+buildUpdate generates the UPDATE statement (and its arguments) for arg. If cols
+are given, only those columns (and the version) are written.
+*/
+func buildUpdate(d Dialect, nulls NullPolicy, table, sqlIdFieldName string, arg interface{}, cols ...string) (string, []interface{}, error) {
 	val := indirectV(reflect.ValueOf(arg))
 	ty := val.Type()
 
-	fieldMap, er := sqlToGoFields(ty)
+	tm, er := sqlToGoFields(ty)
 	if er != nil {
-		return 0, er
+		return "", nil, er
+	}
+
+	keys, er := keyFields(tm, sqlIdFieldName)
+	if er != nil {
+		return "", nil, er
+	}
+
+	ids, er := keyValues(val, keys)
+	if er != nil {
+		return "", nil, er
 	}
 
-	sqlFields := make([]string, len(fieldMap))[:0]
-	newValues := make([]interface{}, len(fieldMap))[:0]
-	placeholders := make([]string, len(fieldMap))[:0]
+	if tm.version != nil && reflect.ValueOf(arg).Kind() != reflect.Ptr {
+		return "", nil, fieldError(tm.version, fmt.Errorf("%T is %w, cannot bump its version", arg, ErrNotPointer))
+	}
+
+	only, er := updateOnly(tm, keys, cols)
+	if er != nil {
+		return "", nil, er
+	}
+
+	sqlFields := make([]string, len(tm.Fields))[:0]
+	newValues := make([]interface{}, len(tm.Fields))[:0]
 
-	for sqlName, meta := range fieldMap {
-		if sqlName == sqlIdFieldName {
+	for i := range tm.Fields {
+		meta := &tm.Fields[i]
+
+		switch {
+		case isKey(meta, keys):
 			continue
+
+		case meta == tm.version:
+			newValues = append(newValues, intId(val.FieldByIndex(meta.Index)) + 1)
+
+		case !meta.updated():
+			continue
+
+		case only != nil && !only[meta]:
+			continue
+
+		case only == nil && meta.OmitEmpty && isZero(val.FieldByIndex(meta.Index)):
+			continue
+
+		default:
+			newValues = append(newValues, columnValue(val, meta, nulls))
 		}
 
-		goName := meta.GoName
-		fieldVal := val.FieldByName(goName).Interface()
+		sqlFields = append(sqlFields, fmt.Sprintf("%s = %s", d.Quote(meta.SqlName), d.Placeholder(len(newValues))))
+	}
 
-		if timeVal, ok := fieldVal.(time.Time) ; ok && meta.Unix {
-			fieldVal = timeVal.Unix()
+	if len(sqlFields) == 0 {
+		return "", nil, fmt.Errorf("no columns of %s to update", tm.Type)
+	}
+
+	where := whereKey(d, keys, len(newValues) + 1)
+	args := append(newValues, ids...)
+
+	if tm.version != nil && !isKey(tm.version, keys) {
+		args = append(args, intId(val.FieldByIndex(tm.version.Index)))
+		where += fmt.Sprintf(" AND %s = %s", d.Quote(tm.version.SqlName), d.Placeholder(len(args)))
+	}
+
+	q := fmt.Sprintf("UPDATE %s SET %s WHERE %s", quoteName(d, table), strings.Join(sqlFields, ", "), where)
+	return q, args, nil
+}
 
-		} else if timeVal, ok := fieldVal.(*time.Time) ; ok && meta.Unix && timeVal != nil {
-			fieldVal = timeVal.Unix()
+/*
+updateOnly resolves the columns passed to UpdateColumns to their fields, or
+returns nil if there are none (and every column is to be written).
+*/
+func updateOnly(tm *typeMeta, keys []*fieldMeta, cols []string) (map[*fieldMeta]bool, error) {
+	if len(cols) == 0 {
+		return nil, nil
+	}
+
+	only := make(map[*fieldMeta]bool, len(cols))
+
+	for _, col := range cols {
+		meta, ok := tm.lookup(col)
+		if !ok {
+			return nil, &Error{Column: col, Err: fmt.Errorf("%w of %s", ErrUnknownColumn, tm.Type)}
+		}
+
+		if isKey(meta, keys) {
+			return nil, fieldError(meta, fmt.Errorf("key columns can't be updated"))
+		}
+
+		if !meta.updated() {
+			return nil, fieldError(meta, fmt.Errorf("readonly and insertonly columns can't be updated"))
 		}
 
-		sqlFields = append(sqlFields, sqlName)
-		newValues = append(newValues, fieldVal)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", len(placeholders) + 1))
+		only[meta] = true
 	}
 
-	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(sqlFields, ", "), strings.Join(placeholders, ", "))
+	return only, nil
+}
 
-	res, er := db.Exec(q, newValues...)
+/*
+buildInsert generates the INSERT statement (and its arguments) for arg. The id
+column and any extra returning columns are left for the database to fill in,
+and fetched back if the Dialect uses RETURNING or OUTPUT.
+*/
+func buildInsert(d Dialect, nulls NullPolicy, table, sqlIdFieldName string, arg interface{}, returning []string) (string, []interface{}, error) {
+	val := indirectV(reflect.ValueOf(arg))
+
+	tm, er := sqlToGoFields(val.Type())
 	if er != nil {
-		return 0, er
+		return "", nil, er
+	}
+
+	returned := returning
+
+	if sqlIdFieldName != "" {
+		returned = append([]string{sqlIdFieldName}, returning...)
+	}
+
+	sqlFields, newValues := insertColumns(val, tm, nulls, true, returned...)
+	outputClause, returningClause := returningClauses(d, returned)
+
+	q := fmt.Sprintf("INSERT INTO %s (%s)%s VALUES (%s)%s", quoteName(d, table), quoteColumns(d, sqlFields), outputClause, placeholders(d, 1, len(newValues)), returningClause)
+	return q, newValues, nil
+}
+
+/*
+returningClauses returns the OUTPUT and RETURNING clauses (at most one of which
+is non-empty) used to fetch cols back after an INSERT.
+*/
+func returningClauses(d Dialect, cols []string) (output, returning string) {
+	if len(cols) == 0 {
+		return "", ""
+	}
+
+	switch d.Returning() {
+	case ReturnClause:
+		returning = " RETURNING " + quoteColumns(d, cols)
+
+	case ReturnOutput:
+		inserted := make([]string, len(cols))
+
+		for i, col := range cols {
+			inserted[i] = "INSERTED." + d.Quote(col)
+		}
+
+		output = " OUTPUT " + strings.Join(inserted, ", ")
+	}
+
+	return output, returning
+}
+
+/*
+insertColumns returns the names and values of the columns written by an INSERT
+of val, in declaration order, skipping readonly fields, the (generated) columns
+in skip and, if omitEmpty is set, zero-valued fields tagged "omitempty".
+*/
+func insertColumns(val reflect.Value, tm *typeMeta, nulls NullPolicy, omitEmpty bool, skip ...string) ([]string, []interface{}) {
+	sqlFields := make([]string, len(tm.Fields))[:0]
+	newValues := make([]interface{}, len(tm.Fields))[:0]
+
+fields:
+	for i := range tm.Fields {
+		meta := &tm.Fields[i]
+
+		if !meta.inserted() {
+			continue
+		}
+
+		for _, col := range skip {
+			if meta.SqlName == col {
+				continue fields
+			}
+		}
+
+		if omitEmpty && meta.OmitEmpty && isZero(val.FieldByIndex(meta.Index)) {
+			continue
+		}
+
+		sqlFields = append(sqlFields, meta.SqlName)
+		newValues = append(newValues, columnValue(val, meta, nulls))
+	}
+
+	return sqlFields, newValues
+}
+
+/* quoteColumns quotes each of cols and joins them with commas. */
+func quoteColumns(d Dialect, cols []string) string {
+	quoted := make([]string, len(cols))
+
+	for i, col := range cols {
+		quoted[i] = d.Quote(col)
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+/* placeholders returns n comma-separated placeholders, numbered from first. */
+func placeholders(d Dialect, first, n int) string {
+	ps := make([]string, n)
+
+	for i := range ps {
+		ps[i] = d.Placeholder(first + i)
+	}
+
+	return strings.Join(ps, ", ")
+}
+
+/*
+fieldValue extracts the value to be written to SQL for the field described by
+meta. Types implementing driver.Valuer are passed through for database/sql to
+convert; otherwise unix times are converted to integers.
+*/
+func fieldValue(val reflect.Value, meta *fieldMeta) interface{} {
+	field := val.FieldByIndex(meta.Index)
+
+	switch meta.valuer {
+	case valuerDirect:
+		return field.Interface()
+
+	case valuerPtr:
+		ptr := reflect.New(field.Type())
+		ptr.Elem().Set(field)
+		return ptr.Interface()
+	}
+
+	fieldVal := field.Interface()
+
+	if timeVal, ok := fieldVal.(time.Time) ; ok && meta.Unix {
+		fieldVal = timeVal.Unix()
+
+	} else if timeVal, ok := fieldVal.(*time.Time) ; ok && meta.Unix && timeVal != nil {
+		fieldVal = timeVal.Unix()
+	}
+
+	return driverValue(fieldVal)
+}
+
+/*
+columnValue is fieldValue for values written to (non-key) columns: zero values
+are written as NULL if nulls or the field's tag ask for it.
+*/
+func columnValue(val reflect.Value, meta *fieldMeta, nulls NullPolicy) interface{} {
+	if meta.writesNull(nulls) && isZero(val.FieldByIndex(meta.Index)) {
+		return nil
 	}
 
-	return res.LastInsertId()
+	return fieldValue(val, meta)
 }
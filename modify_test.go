@@ -0,0 +1,254 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+/* recordDb wraps a DbIshContext and records every statement sent through it. */
+type recordDb struct {
+	DbIshContext
+	queries []string
+}
+
+func (r *recordDb) ExecContext(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+	r.queries = append(r.queries, q)
+	return r.DbIshContext.ExecContext(ctx, q, args...)
+}
+
+func (r *recordDb) QueryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	r.queries = append(r.queries, q)
+	return r.DbIshContext.QueryContext(ctx, q, args...)
+}
+
+func TestColumnOrder(t *testing.T) {
+	f := newFoo()
+	f.Id = 3
+
+	expected := map[Dialect][]string{
+		SQLite: {
+			`INSERT INTO "foo" ("foo_num", "foo_str", "foo_time") VALUES ($1, $2, $3)`,
+			`INSERT INTO "foo" ("foo_id", "foo_num", "foo_str", "foo_time") VALUES ($1, $2, $3, $4)`,
+			`UPDATE "foo" SET "foo_num" = $1, "foo_str" = $2, "foo_time" = $3 WHERE "foo_id" = $4`,
+		},
+		Postgres: {
+			`INSERT INTO "foo" ("foo_num", "foo_str", "foo_time") VALUES ($1, $2, $3) RETURNING "foo_id"`,
+			`INSERT INTO "foo" ("foo_id", "foo_num", "foo_str", "foo_time") VALUES ($1, $2, $3, $4)`,
+			`UPDATE "foo" SET "foo_num" = $1, "foo_str" = $2, "foo_time" = $3 WHERE "foo_id" = $4`,
+		},
+		MySQL: {
+			"INSERT INTO `foo` (`foo_num`, `foo_str`, `foo_time`) VALUES (?, ?, ?)",
+			"INSERT INTO `foo` (`foo_id`, `foo_num`, `foo_str`, `foo_time`) VALUES (?, ?, ?, ?)",
+			"UPDATE `foo` SET `foo_num` = ?, `foo_str` = ?, `foo_time` = ? WHERE `foo_id` = ?",
+		},
+		MSSQL: {
+			`INSERT INTO [foo] ([foo_num], [foo_str], [foo_time]) OUTPUT INSERTED.[foo_id] VALUES (@p1, @p2, @p3)`,
+			`INSERT INTO [foo] ([foo_id], [foo_num], [foo_str], [foo_time]) VALUES (@p1, @p2, @p3, @p4)`,
+			`UPDATE [foo] SET [foo_num] = @p1, [foo_str] = @p2, [foo_time] = @p3 WHERE [foo_id] = @p4`,
+		},
+	}
+
+	for d, stmts := range expected {
+		/* Run each a few times; map iteration order would show up here */
+		for i := 0 ; i < 10 ; i += 1 {
+			q, args, er := buildInsert(d, NullStrict, "foo", "foo_id", f, nil)
+			if er != nil {
+				t.Fatal(er)
+			}
+
+			if q != stmts[0] {
+				t.Fatalf("insert mismatch:\ne: %s\na: %s", stmts[0], q)
+			}
+
+			if args[0] != f.Num || args[1] != f.Str {
+				t.Fatalf("insert args out of order: %#v", args)
+			}
+
+			if q, _, er = buildInsert(d, NullStrict, "foo", "", f, nil) ; er != nil {
+				t.Fatal(er)
+
+			} else if q != stmts[1] {
+				t.Fatalf("insert (no id) mismatch:\ne: %s\na: %s", stmts[1], q)
+			}
+
+			q, args, er = buildUpdate(d, NullStrict, "foo", "foo_id", f)
+			if er != nil {
+				t.Fatal(er)
+			}
+
+			if q != stmts[2] {
+				t.Fatalf("update mismatch:\ne: %s\na: %s", stmts[2], q)
+			}
+
+			if args[0] != f.Num || args[1] != f.Str || args[3] != f.Id {
+				t.Fatalf("update args out of order: %#v", args)
+			}
+		}
+	}
+}
+
+func TestColumnOrderExecuted(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	rec := &recordDb{DbIshContext: db}
+	ctx := context.Background()
+
+	for i := 0 ; i < 5 ; i += 1 {
+		f := newFoo()
+
+		if f.Id, er = InsertContext(ctx, rec, "foo", "foo_id", f) ; er != nil {
+			t.Fatal(er)
+		}
+
+		if er := UpdateContext(ctx, rec, "foo", "foo_id", f) ; er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	for i, q := range rec.queries {
+		if q != rec.queries[i % 2] {
+			t.Errorf("Statement %d differs from the first of its kind:\ne: %s\na: %s", i, rec.queries[i % 2], q)
+		}
+	}
+}
+
+type DefaultFoo struct {
+	Id int64 `crud:"d_id,pk"`
+	Num int64 `crud:"d_num,omitempty"`
+	Str string `crud:"d_str,omitempty"`
+}
+
+func TestUpdateColumns(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := newFoo()
+
+	if f.Id, er = Insert(db, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	q, args, er := buildUpdate(SQLite, NullStrict, "foo", "foo_id", f, "foo_str")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `UPDATE "foo" SET "foo_str" = $1 WHERE "foo_id" = $2` ; q != expected {
+		t.Errorf("SQL mismatch\n%s\n%s", q, expected)
+	}
+
+	if len(args) != 2 || args[0] != f.Str || args[1] != f.Id {
+		t.Errorf("Args mismatch: %#v", args)
+	}
+
+	/* Concurrent changes to other columns survive */
+	stale := f
+	stale.Num = 0
+	stale.Str = "patched"
+
+	if er := UpdateColumns(db, "foo", "foo_id", stale, "foo_str") ; er != nil {
+		t.Fatal(er)
+	}
+
+	var stored Foo
+
+	if er := Get(db, "foo", "foo_id", f.Id, &stored) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if stored.Num != f.Num || stored.Str != "patched" {
+		t.Errorf("UpdateColumns - unexpected record %#v", stored)
+	}
+
+	if er := UpdateColumns(db, "foo", "foo_id", f, "nope") ; er == nil {
+		t.Errorf("UpdateColumns - unknown column accepted")
+	}
+
+	if er := UpdateColumns(db, "foo", "foo_id", f, "foo_id") ; er == nil {
+		t.Errorf("UpdateColumns - key column accepted")
+	}
+
+	if er := UpdateColumns(db, "foo", "foo_id", f) ; er == nil {
+		t.Errorf("UpdateColumns - no columns accepted")
+	}
+}
+
+func TestOmitEmpty(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	_, er = db.Exec(`
+		CREATE TABLE dfoo
+			( d_id INTEGER PRIMARY KEY
+			, d_num INTEGER NOT NULL DEFAULT 7
+			, d_str VARCHAR(32) NOT NULL DEFAULT 'default'
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	q, _, er := buildInsert(SQLite, NullStrict, "dfoo", "d_id", DefaultFoo{Str: "str"}, nil)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `INSERT INTO "dfoo" ("d_str") VALUES ($1)` ; q != expected {
+		t.Errorf("SQL mismatch\n%s\n%s", q, expected)
+	}
+
+	f := DefaultFoo{Str: "str"}
+
+	if f.Id, er = Insert(db, "dfoo", "d_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Reload(db, "dfoo", "", &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Num != 7 || f.Str != "str" {
+		t.Errorf("Insert - DEFAULT not applied: %#v", f)
+	}
+
+	if er := Update(db, "dfoo", "", DefaultFoo{Id: f.Id, Num: 8}) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Reload(db, "dfoo", "", &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Num != 8 || f.Str != "str" {
+		t.Errorf("Update - empty field written: %#v", f)
+	}
+
+	if er := Update(db, "dfoo", "", DefaultFoo{Id: f.Id}) ; er == nil {
+		t.Errorf("Update with nothing to write succeeded")
+	}
+
+	/* Named columns are written even if empty */
+	if er := UpdateColumns(db, "dfoo", "", DefaultFoo{Id: f.Id}, "d_num") ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Reload(db, "dfoo", "", &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Num != 0 {
+		t.Errorf("UpdateColumns - empty field not written: %#v", f)
+	}
+}
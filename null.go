@@ -0,0 +1,307 @@
+package crud
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+/*
+nullKind describes which sql.Null* wrapper (if any) a field is scanned through
+before being assigned.
+*/
+type nullKind int
+
+const (
+	nullNone nullKind = iota
+	nullInt
+	nullUint
+	nullFloat
+	nullBool
+	nullString
+	nullBytes
+	nullTime
+	nullUnix
+	nullByteArray
+	nullByteArrayPtr
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+/*
+NullPolicy controls what happens to NULLs headed for fields that can't hold
+them (anything but pointers, interfaces and sql.Scanner's), which database/sql
+refuses to scan. This is common with LEFT JOINs:
+
+	rows, _ := db.Query("SELECT * FROM foo LEFT JOIN bar ON bar_foo_id = foo_id")
+	crud.Scan(rows, &foo, &bar)
+
+The policy is set package-wide with DefaultNullPolicy or per Handle. A field
+tagged with the "nullzero" option always behaves as if the policy were
+NullAsZero, and one tagged "zeronull" as if it were ZeroAsNull.
+*/
+type NullPolicy int
+
+const (
+	/* NullDefault defers to DefaultNullPolicy. */
+	NullDefault NullPolicy = iota
+
+	/* NullStrict leaves NULLs to database/sql, so scanning one fails. */
+	NullStrict
+
+	/* NullAsZero scans NULL as the field's zero value. */
+	NullAsZero
+
+	/*
+	ZeroAsNull is NullAsZero, and also writes zero values as NULL when inserting
+	and updating (key columns excepted).
+	*/
+	ZeroAsNull
+)
+
+/*
+DefaultNullPolicy is used by Scan and friends, and by Handles without a
+NullPolicy of their own.
+*/
+var DefaultNullPolicy NullPolicy = NullStrict
+
+/* zeroesNull reports whether a NULL scanned into the field becomes its zero value. */
+func (meta *fieldMeta) zeroesNull(nulls NullPolicy) bool {
+	return meta.zeroable && (meta.NullZero || meta.ZeroNull || nulls == NullAsZero || nulls == ZeroAsNull)
+}
+
+/* writesNull reports whether the field's zero value is written as NULL. */
+func (meta *fieldMeta) writesNull(nulls NullPolicy) bool {
+	return meta.zeroable && meta.valuer == valuerNone && !meta.PK && !meta.Version && (meta.ZeroNull || nulls == ZeroAsNull)
+}
+
+/*
+nullKindOf picks the sql.Null* wrapper used to scan the field. Types that
+implement sql.Scanner (through a value or pointer) handle themselves, so they
+are scanned directly whatever the tag options say.
+
+Pointers to every basic kind, to time.Time, to []byte and to byte arrays
+(including named types with those underlying types) are scanned through a
+wrapper, so that NULL becomes a nil pointer.
+*/
+func nullKindOf(meta fieldMeta) nullKind {
+	if implementsScanner(meta.Type) {
+		return nullNone
+	}
+
+	if meta.Unix {
+		return nullUnix
+	}
+
+	if meta.Type.Kind() == reflect.Array && meta.Type.Elem().Kind() == reflect.Uint8 {
+		return nullByteArray
+	}
+
+	if meta.Type.Kind() != reflect.Ptr {
+		return nullNone
+	}
+
+	elem := meta.Type.Elem()
+
+	switch elem.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return nullInt
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return nullUint
+
+	case reflect.Float32, reflect.Float64:
+		return nullFloat
+
+	case reflect.Bool:
+		return nullBool
+
+	case reflect.String:
+		return nullString
+
+	case reflect.Slice:
+		if elem.Elem().Kind() == reflect.Uint8 {
+			return nullBytes
+		}
+
+	case reflect.Array:
+		if elem.Elem().Kind() == reflect.Uint8 {
+			return nullByteArrayPtr
+		}
+
+	case reflect.Struct:
+		if elem.ConvertibleTo(timeType) {
+			return nullTime
+		}
+	}
+
+	return nullNone
+}
+
+/*
+scanTarget returns the value to pass to sql.Rows.Scan for field. If the field
+is scanned through a sql.Null* wrapper (or, when zero is set, a pointer so that
+NULLs can be told apart), assign must be called afterwards to copy the value
+into the field.
+*/
+func (meta *fieldMeta) scanTarget(field reflect.Value, zero bool) interface{} {
+	switch meta.null {
+	case nullNone:
+		if zero {
+			return reflect.New(reflect.PointerTo(meta.Type)).Interface()
+		}
+
+	case nullInt, nullUnix:
+		return new(sql.NullInt64)
+
+	case nullUint:
+		return new(sql.Null[uint64])
+
+	case nullFloat:
+		return new(sql.NullFloat64)
+
+	case nullBool:
+		return new(sql.NullBool)
+
+	case nullString:
+		return new(sql.NullString)
+
+	case nullBytes:
+		return new(sql.Null[[]byte])
+
+	case nullTime:
+		return new(sql.NullTime)
+
+	case nullByteArray, nullByteArrayPtr:
+		return new([]byte)
+	}
+
+	return field.Addr().Interface()
+}
+
+/*
+assign copies a value scanned by scanTarget into field. NULLs set pointer
+fields to nil, and other fields to their zero value if zero is set (and are an
+error otherwise, as they are for fields database/sql scans directly).
+*/
+func (meta *fieldMeta) assign(field reflect.Value, target interface{}, zero bool) error {
+	switch meta.null {
+	case nullNone:
+		ptr := reflect.ValueOf(target).Elem()
+
+		if ptr.IsNil() {
+			field.Set(reflect.Zero(field.Type()))
+
+		} else {
+			field.Set(ptr.Elem())
+		}
+
+	case nullInt:
+		nullInt := target.(*sql.NullInt64)
+		return setNullable(field, nullInt.Valid, nullInt.Int64)
+
+	case nullUint:
+		nullUint := target.(*sql.Null[uint64])
+		return setNullable(field, nullUint.Valid, nullUint.V)
+
+	case nullFloat:
+		nullFloat := target.(*sql.NullFloat64)
+		return setNullable(field, nullFloat.Valid, nullFloat.Float64)
+
+	case nullBool:
+		nullBool := target.(*sql.NullBool)
+		return setNullable(field, nullBool.Valid, nullBool.Bool)
+
+	case nullString:
+		nullString := target.(*sql.NullString)
+		return setNullable(field, nullString.Valid, nullString.String)
+
+	case nullBytes:
+		nullBytes := target.(*sql.Null[[]byte])
+		return setNullable(field, nullBytes.Valid, nullBytes.V)
+
+	case nullTime:
+		nullTime := target.(*sql.NullTime)
+		return setNullable(field, nullTime.Valid, nullTime.Time)
+
+	case nullByteArray:
+		bytes := *target.(*[]byte)
+
+		if bytes != nil {
+			if len(bytes) != field.Len() {
+				return fmt.Errorf("Cannot scan %d bytes into %s", len(bytes), field.Type())
+			}
+
+			reflect.Copy(field, reflect.ValueOf(bytes))
+
+		} else if zero {
+			field.Set(reflect.Zero(field.Type()))
+
+		} else {
+			return fmt.Errorf("Cannot scan NULL into %s", field.Type())
+		}
+
+	case nullByteArrayPtr:
+		bytes := *target.(*[]byte)
+
+		if bytes == nil {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+
+		if len(bytes) != field.Type().Elem().Len() {
+			return fmt.Errorf("Cannot scan %d bytes into %s", len(bytes), field.Type())
+		}
+
+		ptr := reflect.New(field.Type().Elem())
+		reflect.Copy(ptr.Elem(), reflect.ValueOf(bytes))
+		field.Set(ptr)
+
+	case nullUnix:
+		nullInt := target.(*sql.NullInt64)
+
+		if field.Kind() == reflect.Ptr && field.Type().Elem() == timeType {
+			return setNullable(field, nullInt.Valid, time.Unix(nullInt.Int64, 0))
+		}
+
+		if field.Type() != timeType {
+			return fmt.Errorf("Cannot map a unix time to a non-time field (%T)", field.Interface())
+		}
+
+		if nullInt.Valid {
+			field.Set(reflect.ValueOf(time.Unix(nullInt.Int64, 0)))
+
+		} else if zero {
+			field.Set(reflect.Zero(field.Type()))
+
+		} else {
+			return fmt.Errorf("Cannot scan NULL into %s", field.Type())
+		}
+	}
+
+	return nil
+}
+
+/*
+setNullable points the pointer field at a new copy of v (converted to the
+field's element type), or sets it to nil if the value was NULL.
+*/
+func setNullable(field reflect.Value, valid bool, v interface{}) error {
+	if !valid {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	elemType := field.Type().Elem()
+	val := reflect.ValueOf(v)
+
+	if !val.Type().ConvertibleTo(elemType) {
+		return fmt.Errorf("Cannot assign %s to a %s field", val.Type(), field.Type())
+	}
+
+	ptr := reflect.New(elemType)
+	ptr.Elem().Set(val.Convert(elemType))
+	field.Set(ptr)
+	return nil
+}
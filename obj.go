@@ -0,0 +1,171 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+/*
+InsertObj creates a new record for obj, reading the table name and primary key
+from its type rather than taking them as arguments.
+
+The table name comes from a TableName() string method (on the value or pointer
+receiver) or else a blank field tagged with the table name:
+
+	type Foo struct {
+		_ struct{} `crud:"table:foo"`
+		Id int64 `crud:"foo_id,pk"`
+		Num int64 `crud:"foo_num"`
+	}
+
+If exactly one field is tagged "pk", it is an integer and it is unset, the
+database is left to generate it and the new id is written back into obj (which
+must then be a pointer). Otherwise every tagged column, including the key, is
+inserted as-is; a composite key must be fully set.
+*/
+func InsertObj(db DbIsh, obj interface{}) error {
+	return InsertObjContext(context.Background(), handleOf(db), obj)
+}
+
+/* InsertObjContext is the same as InsertObj, but passes ctx through to the database. */
+func InsertObjContext(ctx context.Context, db DbIshContext, obj interface{}) error {
+	val := reflect.ValueOf(obj)
+
+	tm, table, er := objMeta(val.Type())
+	if er != nil {
+		return opError(er, "insert", "", "")
+	}
+
+	return insertObj(ctx, db, tm, table, obj)
+}
+
+/* insertObj does the work of InsertObj once the table name is known. */
+func insertObj(ctx context.Context, db DbIshContext, tm *typeMeta, table string, obj interface{}) error {
+	val := reflect.ValueOf(obj)
+	gen := tm.generatedKey(indirectV(val))
+
+	if gen == nil && len(tm.PKs) > 1 {
+		for _, key := range tm.keys() {
+			if isZero(indirectV(val).FieldByIndex(key.Index)) {
+				er := fmt.Errorf("%w (only a single pk field can be generated by the database)", ErrNoID)
+				return opError(fieldError(key, er), "insert", table, "")
+			}
+		}
+	}
+
+	if gen == nil {
+		_, er := InsertContext(ctx, db, table, "", obj)
+		return er
+	}
+
+	if val.Kind() != reflect.Ptr {
+		er := fmt.Errorf("%T is %w, cannot receive the generated key", obj, ErrNotPointer)
+		return opError(fieldError(gen, er), "insert", table, "")
+	}
+
+	id, er := InsertContext(ctx, db, table, gen.SqlName, obj)
+	if er != nil {
+		return er
+	}
+
+	if id != 0 {
+		setIntId(indirectV(val).FieldByIndex(gen.Index), id)
+	}
+
+	return nil
+}
+
+/*
+UpdateObj syncs obj with its existing record, using the table name and "pk"
+fields declared by its type (see InsertObj). At least one field must be tagged
+"pk", and all of them must be set.
+*/
+func UpdateObj(db DbIsh, obj interface{}) error {
+	return UpdateObjContext(context.Background(), handleOf(db), obj)
+}
+
+/* UpdateObjContext is the same as UpdateObj, but passes ctx through to the database. */
+func UpdateObjContext(ctx context.Context, db DbIshContext, obj interface{}) error {
+	_, table, er := objKeyMeta(reflect.TypeOf(obj))
+	if er != nil {
+		return opError(er, "update", "", "")
+	}
+
+	return UpdateContext(ctx, db, table, "", obj)
+}
+
+/*
+DeleteObj removes obj's record, using the table name and "pk" fields declared
+by its type (see InsertObj), and returns the number of rows affected.
+*/
+func DeleteObj(db DbIsh, obj interface{}) (int64, error) {
+	return DeleteObjContext(context.Background(), handleOf(db), obj)
+}
+
+/* DeleteObjContext is the same as DeleteObj, but passes ctx through to the database. */
+func DeleteObjContext(ctx context.Context, db DbIshContext, obj interface{}) (int64, error) {
+	_, table, er := objKeyMeta(reflect.TypeOf(obj))
+	if er != nil {
+		return 0, opError(er, "delete", "", "")
+	}
+
+	return DeleteContext(ctx, db, table, "", obj)
+}
+
+/* objMeta returns the metadata and declared table name for ty. */
+func objMeta(ty reflect.Type) (*typeMeta, string, error) {
+	tm, er := sqlToGoFields(ty)
+	if er != nil {
+		return nil, "", er
+	}
+
+	if tm.Table == "" {
+		return nil, "", fmt.Errorf("%s declares %w", tm.Type, ErrNoTable)
+	}
+
+	return tm, tm.Table, nil
+}
+
+/* objKeyMeta is objMeta for operations that need at least one "pk" field. */
+func objKeyMeta(ty reflect.Type) (*typeMeta, string, error) {
+	tm, table, er := objMeta(ty)
+	if er != nil {
+		return nil, "", er
+	}
+
+	if len(tm.PKs) == 0 {
+		return nil, "", fmt.Errorf("%s has %w", tm.Type, ErrNoKey)
+	}
+
+	return tm, table, nil
+}
+
+/*
+generatedKey returns the key field the database should generate when
+inserting val: the single "pk" field, if it is an unset integer.
+*/
+func (tm *typeMeta) generatedKey(val reflect.Value) *fieldMeta {
+	if len(tm.PKs) != 1 {
+		return nil
+	}
+
+	meta := &tm.Fields[tm.PKs[0]]
+
+	if !isIntKind(meta.Type.Kind()) || !isZero(val.FieldByIndex(meta.Index)) {
+		return nil
+	}
+
+	return meta
+}
+
+/* keys returns the fields tagged "pk". */
+func (tm *typeMeta) keys() []*fieldMeta {
+	keys := make([]*fieldMeta, len(tm.PKs))
+
+	for i, idx := range tm.PKs {
+		keys[i] = &tm.Fields[idx]
+	}
+
+	return keys
+}
@@ -0,0 +1,139 @@
+package crud
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ObjFoo struct {
+	_ struct{} `crud:"table:foo"`
+	Id int64 `crud:"foo_id,pk"`
+	Num int64 `crud:"foo_num"`
+	Str string `crud:"foo_str"`
+	Time time.Time `crud:"foo_time"`
+}
+
+type NamedMembership struct {
+	UserId int64 `crud:"user_id,pk"`
+	GroupId int64 `crud:"group_id,pk"`
+	Role string `crud:"role"`
+}
+
+func (NamedMembership) TableName() string {
+	return "membership"
+}
+
+type ObjMembership struct {
+	UserId int64 `crud:"user_id,pk"`
+	GroupId int64 `crud:"group_id,pk"`
+	Role string `crud:"role"`
+}
+
+func (*ObjMembership) TableName() string {
+	return "membership"
+}
+
+type NoTableFoo struct {
+	Id int64 `crud:"foo_id,pk"`
+}
+
+type NoPKFoo struct {
+	_ struct{} `crud:"table:foo"`
+	Id int64 `crud:"foo_id"`
+}
+
+func TestObjMeta(t *testing.T) {
+	f := &ObjFoo{}
+
+	if tm, table, er := objMeta(reflect.TypeOf(f)) ; er != nil {
+		t.Fatal(er)
+
+	} else if table != "foo" || len(tm.Fields) != 4 || len(tm.PKs) != 1 {
+		t.Errorf("Unexpected metadata for ObjFoo: %s, %#v", table, tm)
+	}
+
+	if _, table, er := objMeta(reflect.TypeOf(ObjMembership{})) ; er != nil || table != "membership" {
+		t.Errorf("TableName on pointer receiver not honoured: %s, %v", table, er)
+	}
+
+	if er := InsertObj(nil, NoTableFoo{}) ; er == nil {
+		t.Errorf("Expected InsertObj to error without a table name")
+	}
+
+	if er := UpdateObj(nil, NoPKFoo{Id: 1}) ; er == nil {
+		t.Errorf("Expected UpdateObj to error without a pk")
+	}
+}
+
+func TestObjFoo(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := ObjFoo{Num: 1, Str: "obj", Time: time.Unix(1338, 0).UTC()}
+
+	if er := InsertObj(db, f) ; er == nil {
+		t.Errorf("Expected InsertObj to need a pointer to write back the id")
+	}
+
+	if er := InsertObj(db, &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Id == 0 {
+		t.Fatalf("InsertObj did not write back the generated id")
+	}
+
+	f.Num = 2
+
+	if er := UpdateObj(db, f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var f2 ObjFoo
+
+	if er := Get(db, "foo", "", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2.Num != 2 {
+		t.Errorf("UpdateObj did not update: %#v", f2)
+	}
+
+	if n, er := DeleteObj(db, &f) ; er != nil || n != 1 {
+		t.Errorf("DeleteObj failed: %d, %v", n, er)
+	}
+}
+
+func TestObjCompositeKey(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createKeyTables(t, db)
+
+	if er := InsertObj(db, &ObjMembership{UserId: 1, Role: "member"}) ; er == nil {
+		t.Errorf("Expected InsertObj to error on a partially-set composite key")
+	}
+
+	m := NamedMembership{UserId: 1, GroupId: 2, Role: "member"}
+
+	if er := InsertObj(db, m) ; er != nil {
+		t.Fatal(er)
+	}
+
+	m.Role = "admin"
+
+	if er := UpdateObj(db, m) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if n, er := DeleteObj(db, m) ; er != nil || n != 1 {
+		t.Errorf("DeleteObj failed: %d, %v", n, er)
+	}
+}
@@ -0,0 +1,123 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+/*
+WithReturning wraps a Dialect so that generated values are fetched with a
+RETURNING clause instead of LastInsertId. This is for databases and drivers
+that speak an otherwise supported dialect but don't implement LastInsertId
+(e.g. SQLite >= 3.35 or MariaDB through a driver without it):
+
+	h := crud.NewHandle(db, crud.WithReturning(crud.SQLite))
+*/
+func WithReturning(d Dialect) Dialect {
+	return returningDialect{d}
+}
+
+type returningDialect struct {
+	Dialect
+}
+
+func (returningDialect) Returning() ReturningStyle {
+	return ReturnClause
+}
+
+/*
+InsertReturning creates a new record like Insert, but also fetches back the
+given server-generated columns (defaults, timestamps, ...) and scans them,
+along with the id, into obj.
+
+obj must be a pointer to a tagged struct, and every column in returning must be
+tagged on it. Those columns are left out of the INSERT so the database fills
+them in. The values are fetched using RETURNING, or OUTPUT on MSSQL, even if
+the Dialect otherwise uses LastInsertId (MySQL has no such clause at all).
+*/
+func InsertReturning(db DbIsh, table, sqlIdFieldName string, obj interface{}, returning ...string) (int64, error) {
+	return InsertReturningContext(context.Background(), handleOf(db), table, sqlIdFieldName, obj, returning...)
+}
+
+/* InsertReturningContext is the same as InsertReturning, but passes ctx through to the database. */
+func InsertReturningContext(ctx context.Context, db DbIshContext, table, sqlIdFieldName string, obj interface{}, returning ...string) (int64, error) {
+	h := handleOfContext(db)
+
+	if reflect.ValueOf(obj).Kind() != reflect.Ptr {
+		return 0, opError(fmt.Errorf("%T is %w", obj, ErrNotPointer), "insert", table, "")
+	}
+
+	tm, er := sqlToGoFields(reflect.TypeOf(obj))
+	if er != nil {
+		return 0, opError(er, "insert", table, "")
+	}
+
+	for _, col := range returning {
+		if _, ok := tm.lookup(col) ; !ok {
+			return 0, &Error{Op: "insert", Table: table, Column: col, Err: fmt.Errorf("%w of %s, cannot return it", ErrUnknownColumn, tm.Type)}
+		}
+	}
+
+	if h.dialect().Returning() == ReturnLastInsertId {
+		h = NewHandle(h, WithReturning(h.dialect()))
+	}
+
+	return insertReturning(ctx, h, table, sqlIdFieldName, obj, returning)
+}
+
+/*
+insertReturning runs an INSERT whose Dialect fetches generated values back
+with RETURNING or OUTPUT. If arg is a pointer, the returned columns are
+scanned into it; otherwise only the id can be returned.
+*/
+func insertReturning(ctx context.Context, h *Handle, table, sqlIdFieldName string, arg interface{}, returning []string) (int64, error) {
+	q, newValues, er := buildInsert(h.dialect(), h.nullPolicy(), table, sqlIdFieldName, arg, returning)
+	if er != nil {
+		return 0, opError(er, "insert", table, "")
+	}
+
+	if sqlIdFieldName == "" && len(returning) == 0 {
+		_, er := h.ExecContext(ctx, q, newValues...)
+		return 0, opError(er, "insert", table, q)
+	}
+
+	rows, er := h.QueryContext(ctx, q, newValues...)
+	if er != nil {
+		return 0, opError(er, "insert", table, q)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if er := rows.Err() ; er != nil {
+			return 0, opError(er, "insert", table, q)
+		}
+
+		return 0, opError(fmt.Errorf("no rows returned"), "insert", table, q)
+	}
+
+	val := reflect.ValueOf(arg)
+	tm, _ := sqlToGoFields(val.Type())
+	idMeta, idTagged := tm.lookup(sqlIdFieldName)
+
+	var id int64
+
+	if val.Kind() == reflect.Ptr && (idTagged || sqlIdFieldName == "") {
+		/* Only the returned columns come back, and those were checked against arg's tags */
+		cfg := h.scanConfig()
+		cfg.strict = false
+
+		if er := scan(ctx, rows, cfg, arg) ; er != nil {
+			return 0, opError(er, "insert", table, q)
+		}
+
+		if idTagged {
+			id = intId(indirectV(val).FieldByIndex(idMeta.Index))
+		}
+
+	} else if er := rows.Scan(&id) ; er != nil {
+		return 0, opError(er, "insert", table, q)
+	}
+
+	return id, opError(rows.Close(), "insert", table, q)
+}
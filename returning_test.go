@@ -0,0 +1,119 @@
+package crud
+
+import (
+	"testing"
+)
+
+type GenFoo struct {
+	Id int64 `crud:"gen_id"`
+	Name string `crud:"gen_name"`
+	Created int64 `crud:"gen_created"`
+}
+
+func createGenTable(t *testing.T, db DbIsh) {
+	_, er := db.Exec(`
+		CREATE TABLE gen
+			( gen_id INTEGER PRIMARY KEY AUTOINCREMENT
+			, gen_name VARCHAR(32) NOT NULL
+			, gen_created INTEGER NOT NULL DEFAULT 1234
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+}
+
+func TestReturningGoldenSQL(t *testing.T) {
+	g := GenFoo{Name: "gen"}
+
+	expected := map[Dialect]string{
+		WithReturning(SQLite): `INSERT INTO "gen" ("gen_name") VALUES ($1) RETURNING "gen_id", "gen_created"`,
+		Postgres: `INSERT INTO "gen" ("gen_name") VALUES ($1) RETURNING "gen_id", "gen_created"`,
+		MSSQL: `INSERT INTO [gen] ([gen_name]) OUTPUT INSERTED.[gen_id], INSERTED.[gen_created] VALUES (@p1)`,
+	}
+
+	for d, e := range expected {
+		q, args, er := buildInsert(d, NullStrict, "gen", "gen_id", g, []string{"gen_created"})
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		if q != e || len(args) != 1 {
+			t.Errorf("InsertReturning mismatch:\ne: %s\na: %s", e, q)
+		}
+	}
+}
+
+func TestWithReturning(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	h := NewHandle(db, WithReturning(SQLite))
+	f := newFoo()
+
+	id, er := Insert(h, "foo", "foo_id", &f)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if id == 0 || f.Id != id {
+		t.Errorf("Expected RETURNING to write the id back (returned %d, struct has %d)", id, f.Id)
+	}
+
+	f2 := newFoo()
+
+	if id2, er := Insert(h, "foo", "foo_id", f2) ; er != nil {
+		t.Fatal(er)
+
+	} else if id2 != id + 1 {
+		t.Errorf("Expected Insert by value to return the next id, got %d", id2)
+	}
+
+	/* The RETURNING row only holds the id, which mustn't upset strict scans */
+	h.StrictScan = true
+	f3 := newFoo()
+
+	if id3, er := Insert(h, "foo", "foo_id", &f3) ; er != nil {
+		t.Fatal(er)
+
+	} else if f3.Id != id3 || id3 != id + 2 {
+		t.Errorf("Strict RETURNING insert mismatch: returned %d, struct has %d", id3, f3.Id)
+	}
+}
+
+func TestInsertReturning(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createGenTable(t, db)
+
+	g := GenFoo{Name: "gen"}
+
+	if _, er := InsertReturning(db, "gen", "gen_id", g, "gen_created") ; er == nil {
+		t.Errorf("Expected InsertReturning to error on non-pointer")
+	}
+
+	if _, er := InsertReturning(db, "gen", "gen_id", &g, "nope") ; er == nil {
+		t.Errorf("Expected InsertReturning to error on untagged column")
+	}
+
+	id, er := InsertReturning(db, "gen", "gen_id", &g, "gen_created")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if id == 0 || g.Id != id {
+		t.Errorf("InsertReturning id mismatch: returned %d, struct has %d", id, g.Id)
+	}
+
+	if g.Created != 1234 {
+		t.Errorf("InsertReturning did not scan back the default: %d", g.Created)
+	}
+}
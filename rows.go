@@ -0,0 +1,62 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+)
+
+/*
+Rows lazily scans each row of rows into a new T, for use with range:
+
+	rows, _ := db.Query("SELECT * FROM foo")
+
+	for foo, er := range crud.Rows[Foo](rows) {
+		if er != nil {
+			return er
+		}
+
+		...
+	}
+
+Only one row is held in memory at a time. rows is closed when iteration ends,
+including when the loop body breaks out early. Errors (from Scan or, at the end,
+rows.Err) are yielded along with a zero T, after which iteration stops.
+*/
+func Rows[T any](rows *sql.Rows) iter.Seq2[T, error] {
+	return RowsContext[T](context.Background(), rows)
+}
+
+/*
+RowsContext is the same as Rows, but stops (yielding ctx.Err()) as soon as ctx
+is cancelled.
+*/
+func RowsContext[T any](ctx context.Context, rows *sql.Rows) iter.Seq2[T, error] {
+	return rowsContext[T](ctx, rows, defaultScanConfig())
+}
+
+/* rowsContext does the work of RowsContext, scanning according to cfg. */
+func rowsContext[T any](ctx context.Context, rows *sql.Rows, cfg scanConfig) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+
+		var zero T
+
+		for rows.Next() {
+			var obj T
+
+			if er := scanContext(ctx, rows, cfg, &obj) ; er != nil {
+				yield(zero, er)
+				return
+			}
+
+			if !yield(obj, nil) {
+				return
+			}
+		}
+
+		if er := rows.Err() ; er != nil {
+			yield(zero, opError(er, "scan", "", ""))
+		}
+	}
+}
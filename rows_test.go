@@ -0,0 +1,96 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	for i := 0 ; i < 10 ; i += 1 {
+		f := newFoo()
+		f.Num = int64(i)
+
+		if _, er := Insert(db, "foo", "foo_id", f) ; er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	rows, er := db.Query("SELECT * FROM foo ORDER BY foo_num")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	expected := int64(0)
+
+	for f, er := range Rows[Foo](rows) {
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		if f.Num != expected {
+			t.Errorf("Rows out of order: %d != %d", f.Num, expected)
+		}
+
+		expected += 1
+	}
+
+	if expected != 10 {
+		t.Errorf("Rows yielded %d foos, expected 10", expected)
+	}
+
+	rows, er = db.Query("SELECT * FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	for _, er := range Rows[Foo](rows) {
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		break
+	}
+
+	if rows.Next() {
+		t.Errorf("Expected Rows to close the result set on early exit")
+	}
+
+	/* A leaked result set would keep the connection busy, sending the next
+	 * query to a fresh (empty) in-memory database */
+	if c := countFoos(t, db) ; c != 10 {
+		t.Errorf("Expected 10 foos, got %d", c)
+	}
+}
+
+func TestRowsContext(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	if _, er := Insert(db, "foo", "foo_id", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	rows, er := db.Query("SELECT * FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, er := range RowsContext[Foo](ctx, rows) {
+		if !errors.Is(er, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", er)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package crud
 
 import (
+	"context"
 	"fmt"
 	"time"
 	"reflect"
@@ -21,18 +22,56 @@ names of each field in the preceding object.
 If two objects have fields that map to the same column name, only the first is
 assigned properly. If two columns have the same SQL name, the same interface is
 passed for both fields (and which gets bound is undefined). If there is a SQL 
-column which does not map to a Go field (or vice versa), it is ignored silently.
+column which does not map to a Go field (or vice versa), it is ignored silently;
+use ScanStrict to catch these cases instead. Columns of fields tagged
+"writeonly" are skipped. Errors are returned as *Error's (with Op "scan"), and
+nothing is ever printed.
+
+NULLs are handled according to DefaultNullPolicy; use Handle.Scan to apply a
+Handle's NullPolicy instead.
 */
 func Scan(rows *sql.Rows, args ...interface{}) error {
+	return scan(context.Background(), rows, defaultScanConfig(), args...)
+}
+
+/* scanConfig holds the settings that Scan and friends can run with. */
+type scanConfig struct {
+	nulls NullPolicy
+	strict bool
+	hooks []Hook
+}
+
+/* defaultScanConfig returns the settings used by the package-level Scan functions. */
+func defaultScanConfig() scanConfig {
+	return scanConfig{nulls: DefaultNullPolicy}
+}
+
+/* scan does the work of Scan, according to cfg, and reports it to cfg's hooks. */
+func scan(ctx context.Context, rows *sql.Rows, cfg scanConfig, args ...interface{}) error {
+	if len(cfg.hooks) == 0 {
+		return scanRow(rows, cfg, args...)
+	}
+
+	start := time.Now()
+	er := scanRow(rows, cfg, args...)
+
+	e := &ScanEvent{Duration: time.Since(start), Err: er}
+	e.Columns, _ = rows.Columns()
+
+	for _, hook := range cfg.hooks {
+		hook.OnScan(ctx, e)
+	}
+
+	return er
+}
+
+/* scanRow scans the current row of rows into args. */
+func scanRow(rows *sql.Rows, cfg scanConfig, args ...interface{}) error {
 	prefix := ""
 
 	writeBackMap := make(map[string]interface{})
-
-	intRemap := make(map[reflect.Value]*sql.NullInt64)
-	floatRemap := make(map[reflect.Value]*sql.NullFloat64)
-	boolRemap := make(map[reflect.Value]*sql.NullBool)
-	stringRemap := make(map[reflect.Value]*sql.NullString)
-	unixTimeRemap := make(map[reflect.Value]*sql.NullInt64)
+	remaps := []remap{}
+	fields := []scannedField{}
 
 	for _, arg := range args {
 		val := indirectV(reflect.ValueOf(arg))
@@ -43,63 +82,33 @@ func Scan(rows *sql.Rows, args ...interface{}) error {
 			continue
 		}
 
-		fieldMap, er := sqlToGoFields(ty)
+		tm, er := sqlToGoFields(ty)
 		if er != nil {
-			return er
+			return opError(er, "scan", "", "")
 		}
 
-		for sqlName, meta := range fieldMap {
-			goName := meta.GoName
-			sqlName = prefix + sqlName
+		for i := range tm.Fields {
+			meta := &tm.Fields[i]
 
-			field := val.FieldByName(goName)
-			fieldType := field.Type()
-
-			if meta.Unix {
-				nullInt := new(sql.NullInt64)
-				writeBackMap[sqlName] = nullInt
-				unixTimeRemap[field] = nullInt
+			/* writeonly columns are known (so not unmapped), but never read */
+			if !meta.selected() {
+				writeBackMap[prefix + meta.SqlName] = new(interface{})
 				continue
+			}
 
-			} else if fieldType.Kind() == reflect.Ptr {
-				fieldElemKind := fieldType.Elem().Kind()
-
-				switch fieldElemKind {
-				case reflect.Int8:
-					fallthrough
-				case reflect.Int16:
-					fallthrough
-				case reflect.Int32:
-					fallthrough
-				case reflect.Int64:
-					nullInt := new(sql.NullInt64)
-					writeBackMap[sqlName] = nullInt
-					intRemap[field] = nullInt
-					continue
-
-				case reflect.Float32:
-					fallthrough
-				case reflect.Float64:
-					nullFloat := new(sql.NullFloat64)
-					writeBackMap[sqlName] = nullFloat
-					floatRemap[field] = nullFloat
-					continue
-
-				case reflect.Bool:
-					nullBool := new(sql.NullBool)
-					writeBackMap[sqlName] = nullBool
-					boolRemap[field] = nullBool
-					continue
-
-				case reflect.String:
-					nullString := new(sql.NullString)
-					writeBackMap[sqlName] = nullString
-					stringRemap[field] = nullString
-					continue
-				}
+			field := val.FieldByIndex(meta.Index)
+			zero := meta.zeroesNull(cfg.nulls)
+			target := meta.scanTarget(field, zero)
+
+			writeBackMap[prefix + meta.SqlName] = target
+
+			if cfg.strict {
+				fields = append(fields, scannedField{ty.Name() + "." + meta.GoName, prefix + meta.SqlName})
 			}
 
-			writeBackMap[sqlName] = val.FieldByName(goName).Addr().Interface()
+			if meta.null != nullNone || zero {
+				remaps = append(remaps, remap{field, meta, prefix + meta.SqlName, target, zero})
+			}
 		}
 
 		prefix = ""
@@ -107,7 +116,13 @@ func Scan(rows *sql.Rows, args ...interface{}) error {
 
 	cols, er := rows.Columns()
 	if er != nil {
-		return er
+		return opError(er, "scan", "", "")
+	}
+
+	if cfg.strict {
+		if er := checkColumns(cols, writeBackMap, fields) ; er != nil {
+			return opError(er, "scan", "", "")
+		}
 	}
 
 	writeBack := make([]interface{}, len(cols))
@@ -122,78 +137,42 @@ func Scan(rows *sql.Rows, args ...interface{}) error {
 	}
 
 	if er := rows.Scan(writeBack...) ; er != nil {
-		fmt.Printf("Error encountered, columns: %#v\n", cols)
-		return er
-	}
-
-	for field, nullInt := range intRemap {
-		if nullInt.Valid {
-			switch field.Type().Elem().Kind() {
-			case reflect.Int8:
-				tmp := int8(nullInt.Int64)
-				field.Set(reflect.ValueOf(&tmp))
-
-			case reflect.Int16:
-				tmp := int16(nullInt.Int64)
-				field.Set(reflect.ValueOf(&tmp))
-
-			case reflect.Int32:
-				tmp := int32(nullInt.Int64)
-				field.Set(reflect.ValueOf(&tmp))
-
-			case reflect.Int64:
-				field.Set(reflect.ValueOf(&nullInt.Int64))
-			}
-		}
-	}
-
-	for field, nullFloat := range floatRemap {
-		if nullFloat.Valid {
-			switch field.Type().Elem().Kind() {
-			case reflect.Float32:
-				tmp := float32(nullFloat.Float64)
-				field.Set(reflect.ValueOf(&tmp))
-
-			case reflect.Float64:
-				field.Set(reflect.ValueOf(&nullFloat.Float64))
-			}
-		}
+		return opError(er, "scan", "", "")
 	}
 
-	for field, nullBool := range boolRemap {
-		if nullBool.Valid {
-			field.Set(reflect.ValueOf(&nullBool.Bool))
+	for _, r := range remaps {
+		if er := r.meta.assign(r.field, r.target, r.zero) ; er != nil {
+			return &Error{Op: "scan", Column: r.col, Field: r.meta.GoName, Err: er}
 		}
 	}
 
-	for field, nullString := range stringRemap {
-		if nullString.Valid {
-			field.Set(reflect.ValueOf(&nullString.String))
-		}
-	}
-
-	for field, nullInt := range unixTimeRemap {
-		if nullInt.Valid {
-			t := time.Unix(nullInt.Int64, 0)
-
-			if field.Kind() == reflect.Ptr && field.Type().Elem() == reflect.TypeOf(time.Time{}) {
-				if field.IsNil() {
-					newVal := &time.Time{}
-					field.Set(reflect.ValueOf(newVal))
-				}
+	return nil
+}
 
-				field = field.Elem()
-			}
+/* remap records a field that was scanned through a sql.Null* wrapper (or a pointer). */
+type remap struct {
+	field reflect.Value
+	meta *fieldMeta
+	col string
+	target interface{}
+	zero bool
+}
 
-			if field.Type() != reflect.TypeOf(time.Time{}) {
-				return fmt.Errorf("Cannot map a unix time to a non-time field (%T)", field.Interface())
-			}
+/*
+ScanContext is the same as Scan, but returns ctx.Err() instead of scanning if
+ctx has already been cancelled.
+*/
+func ScanContext(ctx context.Context, rows *sql.Rows, args ...interface{}) error {
+	return scanContext(ctx, rows, defaultScanConfig(), args...)
+}
 
-			field.Set(reflect.ValueOf(t))
-		}
+/* scanContext is the same as scan, but checks ctx first. */
+func scanContext(ctx context.Context, rows *sql.Rows, cfg scanConfig, args ...interface{}) error {
+	if er := ctx.Err() ; er != nil {
+		return opError(er, "scan", "", "")
 	}
 
-	return nil
+	return scan(ctx, rows, cfg, args...)
 }
 
 /*
@@ -219,30 +198,44 @@ With simply
   ScanAll(rows, &objs)
 */
 func ScanAll(rows *sql.Rows, slicePtr interface{}) error {
+	return ScanAllContext(context.Background(), rows, slicePtr)
+}
+
+/*
+ScanAllContext is the same as ScanAll, but stops (and returns ctx.Err()) as
+soon as ctx is cancelled. The rows should generally come from a QueryContext
+call using the same ctx.
+*/
+func ScanAllContext(ctx context.Context, rows *sql.Rows, slicePtr interface{}) error {
+	return scanAll(ctx, rows, defaultScanConfig(), slicePtr)
+}
+
+/* scanAll does the work of ScanAllContext, according to cfg. */
+func scanAll(ctx context.Context, rows *sql.Rows, cfg scanConfig, slicePtr interface{}) error {
 	defer rows.Close()
 
 	sliceVal := reflect.ValueOf(slicePtr).Elem()
 
 	if sliceVal.Kind() != reflect.Slice {
-		return fmt.Errorf("Argument to crud.ScanAll is not a slice")
+		return opError(fmt.Errorf("Argument to crud.ScanAll is not a slice"), "scan", "", "")
 	}
 
 	elemType := sliceVal.Type().Elem()
 
 	if elemType.Kind() != reflect.Struct {
-		return fmt.Errorf("Argument to crud.ScanAll must be a slice of structs")
+		return opError(fmt.Errorf("Argument to crud.ScanAll must be a slice of structs: %w", ErrNotStruct), "scan", "", "")
 	}
 
 	for rows.Next() {
 		newVal := reflect.New(elemType)
 
-		if er := Scan(rows, newVal.Interface()) ; er != nil {
+		if er := scanContext(ctx, rows, cfg, newVal.Interface()) ; er != nil {
 			return er
 		}
 
 		sliceVal.Set(reflect.Append(sliceVal, newVal.Elem()))
 	}
 
-	return nil
+	return opError(rows.Err(), "scan", "", "")
 }
 
@@ -1,9 +1,14 @@
 package crud
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
+	"strings"
 	"testing"
 	"database/sql"
+	"database/sql/driver"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -150,7 +155,7 @@ func TestSingleFoo(t *testing.T) {
 	}
 
 	if f.Str != f2.Str {
-		t.Errorf("Scan mismatch, Str: %d != %d", f.Str, f2.Str)
+		t.Errorf("Scan mismatch, Str: %s != %s", f.Str, f2.Str)
 	}
 
 	if !f.Time.Equal(f2.Time) {
@@ -584,3 +589,603 @@ func TestTimeMarshalling(t *testing.T) {
 		}
 	}
 }
+
+func TestContextFoo(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+
+	conn, er := db.Conn(ctx)
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer conn.Close()
+
+	f := newFoo()
+
+	if f.Id, er = InsertContext(ctx, conn, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	f.Num = 5
+
+	if er := UpdateContext(ctx, conn, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	rows, er := conn.QueryContext(ctx, "SELECT * FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []Foo{}
+
+	if er := ScanAllContext(ctx, rows, &foos) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 1 || foos[0].Num != 5 {
+		t.Errorf("Unexpected foos: %#v", foos)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	if _, er := InsertContext(cancelled, db, "foo", "foo_id", f) ; !errors.Is(er, context.Canceled) {
+		t.Errorf("Expected InsertContext to fail with context.Canceled, got %v", er)
+	}
+
+	if er := UpdateContext(cancelled, NewHandle(db, SQLite), "foo", "foo_id", f) ; !errors.Is(er, context.Canceled) {
+		t.Errorf("Expected UpdateContext to fail with context.Canceled, got %v", er)
+	}
+}
+
+/* Csv is stored as a comma-separated string. */
+type Csv []string
+
+func (c Csv) Value() (driver.Value, error) {
+	return strings.Join(c, ","), nil
+}
+
+func (c *Csv) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		*c = strings.Split(src, ",")
+
+	case []byte:
+		*c = strings.Split(string(src), ",")
+
+	default:
+		return fmt.Errorf("Csv: cannot scan %T", src)
+	}
+
+	return nil
+}
+
+/* Upper is stored upper-cased and scanned back lower-cased, via pointer receivers. */
+type Upper string
+
+func (u *Upper) Value() (driver.Value, error) {
+	if u == nil {
+		return nil, nil
+	}
+
+	return strings.ToUpper(string(*u)), nil
+}
+
+func (u *Upper) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case string:
+		*u = Upper(strings.ToLower(src))
+
+	case []byte:
+		*u = Upper(strings.ToLower(string(src)))
+
+	default:
+		return fmt.Errorf("Upper: cannot scan %T", src)
+	}
+
+	return nil
+}
+
+/* Stamp is stored as RFC 3339 text, even when tagged unix. */
+type Stamp struct {
+	time.Time
+}
+
+func (s Stamp) Value() (driver.Value, error) {
+	return s.Format(time.RFC3339), nil
+}
+
+func (s *Stamp) Scan(src interface{}) error {
+	str, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("Stamp: cannot scan %T", src)
+	}
+
+	t, er := time.Parse(time.RFC3339, str)
+	s.Time = t
+	return er
+}
+
+type ScannerFoo struct {
+	Tags Csv `crud:"s_tags"`
+	Upper Upper `crud:"s_upper"`
+	UpperPtr *Upper `crud:"s_upper_ptr"`
+	NilUpperPtr *Upper `crud:"s_upper_nil"`
+	Stamp Stamp `crud:"s_stamp,unix"`
+}
+
+func TestScannerValuer(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	_, er = db.Exec(`
+		CREATE TABLE sfoo
+			( s_tags TEXT NOT NULL
+			, s_upper TEXT NOT NULL
+			, s_upper_ptr TEXT
+			, s_upper_nil TEXT
+			, s_stamp TEXT NOT NULL
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	upper := Upper("pointer")
+
+	f1 := ScannerFoo{
+		Tags: Csv{"a", "b", "c"},
+		Upper: Upper("value"),
+		UpperPtr: &upper,
+		Stamp: Stamp{time.Unix(1338, 0).UTC()},
+	}
+
+	if _, er := Insert(db, "sfoo", "", f1) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var rawTags, rawUpper, rawStamp string
+
+	if er := db.QueryRow("SELECT s_tags, s_upper, s_stamp FROM sfoo").Scan(&rawTags, &rawUpper, &rawStamp) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if rawTags != "a,b,c" || rawUpper != "VALUE" || rawStamp != "1970-01-01T00:22:18Z" {
+		t.Errorf("Valuers not used on insert: %q, %q, %q", rawTags, rawUpper, rawStamp)
+	}
+
+	rows, er := db.Query("SELECT * FROM sfoo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	foos := []ScannerFoo{}
+
+	if er := ScanAll(rows, &foos) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if len(foos) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(foos))
+	}
+
+	f2 := foos[0]
+
+	if strings.Join(f2.Tags, "|") != "a|b|c" {
+		t.Errorf("Tags mismatch: %#v", f2.Tags)
+	}
+
+	if f2.Upper != "value" {
+		t.Errorf("Upper mismatch: %q", f2.Upper)
+	}
+
+	if f2.UpperPtr == nil || *f2.UpperPtr != "pointer" {
+		t.Errorf("UpperPtr mismatch: %#v", f2.UpperPtr)
+	}
+
+	if f2.NilUpperPtr != nil {
+		t.Errorf("NilUpperPtr should be nil: %#v", f2.NilUpperPtr)
+	}
+
+	if !f2.Stamp.Equal(f1.Stamp.Time) {
+		t.Errorf("Stamp mismatch: %v != %v", f2.Stamp, f1.Stamp)
+	}
+}
+
+type MyInt int32
+type MyUint uint16
+type MyString string
+type MyBytes []byte
+type MyTime time.Time
+
+type NullableFoo struct {
+	Int *int `crud:"n_int"`
+	Uint *uint `crud:"n_uint"`
+	Uint8 *uint8 `crud:"n_uint8"`
+	Uint16 *uint16 `crud:"n_uint16"`
+	Uint32 *uint32 `crud:"n_uint32"`
+	Uint64 *uint64 `crud:"n_uint64"`
+	Time *time.Time `crud:"n_time"`
+	Bytes *[]byte `crud:"n_bytes"`
+	Array *[4]byte `crud:"n_array"`
+	MyInt *MyInt `crud:"n_myint"`
+	MyUint *MyUint `crud:"n_myuint"`
+	MyString *MyString `crud:"n_mystring"`
+	MyBytes *MyBytes `crud:"n_mybytes"`
+	MyTime *MyTime `crud:"n_mytime"`
+	NullInt sql.Null[int64] `crud:"n_null_int"`
+	NullString sql.Null[string] `crud:"n_null_string"`
+	NullTime sql.Null[time.Time] `crud:"n_null_time"`
+}
+
+func createNullableTable(t *testing.T, db DbIsh) {
+	_, er := db.Exec(`
+		CREATE TABLE nfoo
+			( n_int INTEGER
+			, n_uint INTEGER
+			, n_uint8 INTEGER
+			, n_uint16 INTEGER
+			, n_uint32 INTEGER
+			, n_uint64 INTEGER
+			, n_time TIMESTAMP
+			, n_bytes BLOB
+			, n_array BLOB
+			, n_myint INTEGER
+			, n_myuint INTEGER
+			, n_mystring VARCHAR(32)
+			, n_mybytes BLOB
+			, n_mytime TIMESTAMP
+			, n_null_int INTEGER
+			, n_null_string VARCHAR(32)
+			, n_null_time TIMESTAMP
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+}
+
+func scanNullable(t *testing.T, db DbIsh, f *NullableFoo) {
+	rows, er := db.Query("SELECT * FROM nfoo")
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		t.Fatal("No rows returned?")
+	}
+
+	if er := Scan(rows, f) ; er != nil {
+		t.Fatal(er)
+	}
+}
+
+func TestNullableTypes(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createNullableTable(t, db)
+
+	var Int int = -1
+	var Uint uint = 1
+	var Uint8 uint8 = 8
+	var Uint16 uint16 = 16
+	var Uint32 uint32 = 32
+	var Uint64 uint64 = 64
+	var Time time.Time = time.Unix(1338, 0).UTC()
+	var Bytes []byte = []byte("bytes")
+	var Array [4]byte = [4]byte{1, 2, 3, 4}
+	var MyInt MyInt = 42
+	var MyUint MyUint = 43
+	var MyString MyString = "named"
+	var MyBytes MyBytes = MyBytes("named bytes")
+	var MyTime MyTime = MyTime(time.Unix(1339, 0).UTC())
+
+	f1 := NullableFoo{
+		Int: &Int,
+		Uint: &Uint,
+		Uint8: &Uint8,
+		Uint16: &Uint16,
+		Uint32: &Uint32,
+		Uint64: &Uint64,
+		Time: &Time,
+		Bytes: &Bytes,
+		Array: &Array,
+		MyInt: &MyInt,
+		MyUint: &MyUint,
+		MyString: &MyString,
+		MyBytes: &MyBytes,
+		MyTime: &MyTime,
+		NullInt: sql.Null[int64]{V: 7, Valid: true},
+		NullString: sql.Null[string]{V: "null", Valid: true},
+		NullTime: sql.Null[time.Time]{V: Time, Valid: true},
+	}
+
+	if _, er := Insert(db, "nfoo", "", f1) ; er != nil {
+		t.Fatal(er)
+	}
+
+	f2 := NullableFoo{}
+	scanNullable(t, db, &f2)
+
+	if f2.Int == nil || *f2.Int != Int {
+		t.Errorf("Int - mismatch")
+	}
+
+	if f2.Uint == nil || *f2.Uint != Uint {
+		t.Errorf("Uint - mismatch")
+	}
+
+	if f2.Uint8 == nil || *f2.Uint8 != Uint8 {
+		t.Errorf("Uint8 - mismatch")
+	}
+
+	if f2.Uint16 == nil || *f2.Uint16 != Uint16 {
+		t.Errorf("Uint16 - mismatch")
+	}
+
+	if f2.Uint32 == nil || *f2.Uint32 != Uint32 {
+		t.Errorf("Uint32 - mismatch")
+	}
+
+	if f2.Uint64 == nil || *f2.Uint64 != Uint64 {
+		t.Errorf("Uint64 - mismatch")
+	}
+
+	if f2.Time == nil || !f2.Time.Equal(Time) {
+		t.Errorf("Time - mismatch")
+	}
+
+	if f2.Bytes == nil || string(*f2.Bytes) != string(Bytes) {
+		t.Errorf("Bytes - mismatch")
+	}
+
+	if f2.Array == nil || *f2.Array != Array || f2.Array == f1.Array {
+		t.Errorf("Array - mismatch")
+	}
+
+	if f2.MyInt == nil || *f2.MyInt != MyInt {
+		t.Errorf("MyInt - mismatch")
+	}
+
+	if f2.MyUint == nil || *f2.MyUint != MyUint {
+		t.Errorf("MyUint - mismatch")
+	}
+
+	if f2.MyString == nil || *f2.MyString != MyString {
+		t.Errorf("MyString - mismatch")
+	}
+
+	if f2.MyBytes == nil || string(*f2.MyBytes) != string(MyBytes) {
+		t.Errorf("MyBytes - mismatch")
+	}
+
+	if f2.MyTime == nil || !time.Time(*f2.MyTime).Equal(time.Time(MyTime)) {
+		t.Errorf("MyTime - mismatch")
+	}
+
+	if !f2.NullInt.Valid || f2.NullInt.V != 7 {
+		t.Errorf("NullInt - mismatch")
+	}
+
+	if !f2.NullString.Valid || f2.NullString.V != "null" {
+		t.Errorf("NullString - mismatch")
+	}
+
+	if !f2.NullTime.Valid || !f2.NullTime.V.Equal(Time) {
+		t.Errorf("NullTime - mismatch")
+	}
+
+	/* Scanning NULLs over a populated struct must clear it */
+	if _, er := db.Exec("DELETE FROM nfoo") ; er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := Insert(db, "nfoo", "", NullableFoo{}) ; er != nil {
+		t.Fatal(er)
+	}
+
+	scanNullable(t, db, &f2)
+
+	if f2.Int != nil || f2.Uint != nil || f2.Uint8 != nil || f2.Uint16 != nil || f2.Uint32 != nil || f2.Uint64 != nil {
+		t.Errorf("Integer pointers - not nil")
+	}
+
+	if f2.Time != nil || f2.Bytes != nil || f2.Array != nil {
+		t.Errorf("Time/Bytes/Array - not nil")
+	}
+
+	if f2.MyInt != nil || f2.MyUint != nil || f2.MyString != nil || f2.MyBytes != nil || f2.MyTime != nil {
+		t.Errorf("Named pointers - not nil")
+	}
+
+	if f2.NullInt.Valid || f2.NullString.Valid || f2.NullTime.Valid {
+		t.Errorf("sql.Null[T] - valid")
+	}
+}
+
+type ZeroFoo struct {
+	Id int64 `crud:"z_id"`
+	Num int64 `crud:"z_num"`
+	Str string `crud:"z_str"`
+	Time time.Time `crud:"z_time"`
+	UnixTime time.Time `crud:"z_unix,unix"`
+	MyInt MyInt `crud:"z_myint"`
+}
+
+/* ArrayZeroFoo has fields that aren't scanned directly by database/sql */
+type ArrayZeroFoo struct {
+	Bytes [4]byte `crud:"z_bytes"`
+	UnixTime time.Time `crud:"z_unix,unix"`
+}
+
+type TaggedZeroFoo struct {
+	Id int64 `crud:"z_id"`
+	Num int64 `crud:"z_num,nullzero"`
+	Str string `crud:"z_str,zeronull"`
+}
+
+func createZeroTable(t *testing.T, db DbIsh) {
+	_, er := db.Exec(`
+		CREATE TABLE zfoo
+			( z_id INTEGER PRIMARY KEY
+			, z_num INTEGER
+			, z_str VARCHAR(32)
+			, z_time TIMESTAMP
+			, z_unix INTEGER
+			, z_myint INTEGER
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+}
+
+func TestNullPolicy(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createZeroTable(t, db)
+
+	if _, er := db.Exec("INSERT INTO zfoo (z_id) VALUES (1)") ; er != nil {
+		t.Fatal(er)
+	}
+
+	f := ZeroFoo{Num: 1, Str: "stale", Time: time.Now(), UnixTime: time.Now(), MyInt: 1}
+
+	if er := Get(db, "zfoo", "z_id", 1, &f) ; er == nil {
+		t.Errorf("NullStrict - scanned NULL into an int64")
+	}
+
+	h := &Handle{Dialect: SQLite, NullPolicy: NullAsZero}
+	h = h.Bind(db)
+
+	if er := Get(h, "zfoo", "z_id", 1, &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Num != 0 || f.Str != "" || !f.Time.IsZero() || !f.UnixTime.IsZero() || f.MyInt != 0 {
+		t.Errorf("NullAsZero - fields not zeroed: %#v", f)
+	}
+
+	/* A tagged field zeroes NULLs whatever the policy */
+	tf := TaggedZeroFoo{Num: 1, Str: "stale"}
+
+	rows, er := db.Query("SELECT * FROM zfoo")
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	tfs := []TaggedZeroFoo{tf}
+
+	if er := ScanAll(rows, &tfs) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if len(tfs) != 2 || tfs[1].Num != 0 || tfs[1].Str != "" {
+		t.Errorf("nullzero - fields not zeroed: %#v", tfs)
+	}
+
+	/* ZeroAsNull writes zero values back as NULL */
+	h.NullPolicy = ZeroAsNull
+
+	f = ZeroFoo{Str: "str"}
+
+	id, er := Insert(h, "zfoo", "z_id", f)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	var nulls int
+
+	if er := db.QueryRow("SELECT COUNT(*) FROM zfoo WHERE z_id = $1 AND z_num IS NULL AND z_str IS NOT NULL AND z_time IS NULL AND z_unix IS NULL", id).Scan(&nulls) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if nulls != 1 {
+		t.Errorf("ZeroAsNull - zero values not inserted as NULL")
+	}
+
+	f.Id = id
+	f.Num = 2
+	f.Str = ""
+
+	if er := Update(h, "zfoo", "z_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := db.QueryRow("SELECT COUNT(*) FROM zfoo WHERE z_id = $1 AND z_num = 2 AND z_str IS NULL", id).Scan(&nulls) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if nulls != 1 {
+		t.Errorf("ZeroAsNull - zero values not updated to NULL")
+	}
+
+	/* ... as does a field tagged zeronull, with the default policy, but not one tagged nullzero */
+	if _, er := Insert(db, "zfoo", "z_id", TaggedZeroFoo{}) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := db.QueryRow("SELECT COUNT(*) FROM zfoo WHERE z_num = 0 AND z_str IS NULL").Scan(&nulls) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if nulls != 1 {
+		t.Errorf("nullzero/zeronull - zero values not inserted as 0 and NULL")
+	}
+
+	/* NULLs into byte arrays and unix times are errors too, rather than leaving stale values */
+	for _, q := range []string{"SELECT NULL AS z_bytes, 1338 AS z_unix", "SELECT x'01020304' AS z_bytes, NULL AS z_unix"} {
+		a := ArrayZeroFoo{Bytes: [4]byte{9, 9, 9, 9}, UnixTime: time.Unix(1, 0)}
+
+		rows, er := db.Query(q)
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		if !rows.Next() {
+			t.Fatal(rows.Err())
+		}
+
+		if er := Scan(rows, &a) ; er == nil {
+			t.Errorf("NullStrict - scanned NULL from %q: %#v", q, a)
+		}
+
+		rows.Close()
+
+		if rows, er = db.Query(q) ; er != nil {
+			t.Fatal(er)
+		}
+
+		if !rows.Next() {
+			t.Fatal(rows.Err())
+		}
+
+		/* h zeroes NULLs (ZeroAsNull reads them as zero values) */
+		if er := h.Scan(rows, &a) ; er != nil || (a.Bytes != [4]byte{} && !a.UnixTime.IsZero()) {
+			t.Errorf("ZeroAsNull - NULL from %q not zeroed: %#v, %v", q, a, er)
+		}
+
+		rows.Close()
+	}
+}
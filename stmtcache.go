@@ -0,0 +1,249 @@
+package crud
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+)
+
+/*
+StmtCache keeps prepared statements for the SQL run through it, so that
+repeated statements (such as the Insert and Update of a given type, which
+always generate the same SQL) are only parsed by the database once:
+
+	cache := crud.NewStmtCache(db, 64)
+	defer cache.Close()
+
+	h := crud.NewHandle(cache, crud.Postgres)
+	crud.Update(h, "foo", "foo_id", f)
+
+A StmtCache is a DbIsh bound to the *sql.DB or *sql.Tx it was created with;
+statements prepared on a Tx are only valid until it ends, so a cache for a Tx
+should be closed along with it. It is safe for concurrent use (if the
+underlying DbIsh is).
+
+Once the cache holds size statements, the least recently used one is evicted
+to make room for the next, and closed as soon as nothing is running on it.
+Closing a statement prepared on a *sql.DB leaves its open rows alone, but on a
+Tx (or Conn) it cuts them short, so queries are only cached on a *sql.DB; on
+anything else they are sent to the database unprepared.
+*/
+type StmtCache struct {
+	db DbIshContext
+	size int
+
+	mu sync.Mutex
+	closed bool
+
+	/* cacheQueries is set if db is a *sql.DB, whose statements can be closed under open rows. */
+	cacheQueries bool
+
+	/* lru holds *cachedStmt's, most recently used first. */
+	lru *list.List
+	stmts map[string]*list.Element
+}
+
+type cachedStmt struct {
+	q string
+	stmt *sql.Stmt
+
+	/* users counts the calls running on stmt; it is closed once evicted and unused. */
+	users int
+	evicted bool
+}
+
+/* NewStmtCache returns a StmtCache holding at most size statements prepared on db. */
+func NewStmtCache(db DbIsh, size int) *StmtCache {
+	if size < 1 {
+		size = 1
+	}
+
+	_, isDB := db.(*sql.DB)
+
+	return &StmtCache{
+		db: contextDb(db),
+		size: size,
+		cacheQueries: isDB,
+		lru: list.New(),
+		stmts: make(map[string]*list.Element),
+	}
+}
+
+/* Exec implements DbIsh. */
+func (c *StmtCache) Exec(q string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), q, args...)
+}
+
+/* Prepare implements DbIsh. */
+func (c *StmtCache) Prepare(q string) (*sql.Stmt, error) {
+	return c.PrepareContext(context.Background(), q)
+}
+
+/* Query implements DbIsh. */
+func (c *StmtCache) Query(q string, args ...interface{}) (*sql.Rows, error) {
+	return c.QueryContext(context.Background(), q, args...)
+}
+
+/* ExecContext implements DbIshContext, running q through a cached statement. */
+func (c *StmtCache) ExecContext(ctx context.Context, q string, args ...interface{}) (sql.Result, error) {
+	cs, er := c.acquire(ctx, q)
+	if er != nil {
+		return nil, er
+	}
+
+	if cs == nil {
+		return c.db.ExecContext(ctx, q, args...)
+	}
+	defer c.release(cs)
+
+	return cs.stmt.ExecContext(ctx, args...)
+}
+
+/*
+PrepareContext implements DbIshContext. The statement is prepared afresh (not
+taken from the cache), since the caller is responsible for closing it.
+*/
+func (c *StmtCache) PrepareContext(ctx context.Context, q string) (*sql.Stmt, error) {
+	return c.db.PrepareContext(ctx, q)
+}
+
+/*
+QueryContext implements DbIshContext, running q through a cached statement if
+the cache is bound to a *sql.DB (and directly otherwise).
+*/
+func (c *StmtCache) QueryContext(ctx context.Context, q string, args ...interface{}) (*sql.Rows, error) {
+	if !c.cacheQueries {
+		return c.db.QueryContext(ctx, q, args...)
+	}
+
+	cs, er := c.acquire(ctx, q)
+	if er != nil {
+		return nil, er
+	}
+
+	if cs == nil {
+		return c.db.QueryContext(ctx, q, args...)
+	}
+
+	/* The rows keep the statement alive (within database/sql) until they are closed */
+	defer c.release(cs)
+
+	return cs.stmt.QueryContext(ctx, args...)
+}
+
+/* Len returns the number of statements in the cache. */
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+/*
+Close evicts every cached statement, closing those that aren't in use.
+Statements run through the cache afterwards are sent to the database
+unprepared.
+*/
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+
+	var firstEr error
+
+	for c.lru.Len() > 0 {
+		if er := c.evict(c.lru.Back()) ; er != nil && firstEr == nil {
+			firstEr = er
+		}
+	}
+
+	return firstEr
+}
+
+/*
+acquire returns the cached statement for q, preparing (and caching) it if need
+be, and marks it as in use until release is called. A nil cachedStmt means the
+cache is closed and q should be run directly.
+*/
+func (c *StmtCache) acquire(ctx context.Context, q string) (*cachedStmt, error) {
+	if cs, closed := c.lookup(q) ; cs != nil || closed {
+		return cs, nil
+	}
+
+	/* Prepare without holding the lock; if someone else beats us to it, use theirs */
+	stmt, er := c.db.PrepareContext(ctx, q)
+	if er != nil {
+		return nil, er
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		stmt.Close()
+		return nil, nil
+	}
+
+	if elem, ok := c.stmts[q] ; ok {
+		stmt.Close()
+		return c.use(elem), nil
+	}
+
+	cs := &cachedStmt{q: q, stmt: stmt}
+	c.stmts[q] = c.lru.PushFront(cs)
+	cs.users += 1
+
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+
+	return cs, nil
+}
+
+/* lookup is acquire for statements that are already cached; it also reports whether the cache is closed. */
+func (c *StmtCache) lookup(q string) (*cachedStmt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.stmts[q] ; ok {
+		return c.use(elem), false
+	}
+
+	return nil, c.closed
+}
+
+/* use marks the statement in elem as recently used and in use. c.mu must be held. */
+func (c *StmtCache) use(elem *list.Element) *cachedStmt {
+	c.lru.MoveToFront(elem)
+
+	cs := elem.Value.(*cachedStmt)
+	cs.users += 1
+	return cs
+}
+
+/* release undoes acquire, closing the statement if it was evicted in the meantime. */
+func (c *StmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs.users -= 1
+
+	if cs.evicted && cs.users == 0 {
+		cs.stmt.Close()
+	}
+}
+
+/* evict removes the statement in elem from the cache, closing it if it isn't in use. c.mu must be held. */
+func (c *StmtCache) evict(elem *list.Element) error {
+	cs := c.lru.Remove(elem).(*cachedStmt)
+	delete(c.stmts, cs.q)
+	cs.evicted = true
+
+	if cs.users == 0 {
+		return cs.stmt.Close()
+	}
+
+	return nil
+}
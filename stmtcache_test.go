@@ -0,0 +1,242 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStmtCache(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	cache := NewStmtCache(db, 2)
+	h := NewHandle(cache, SQLite)
+
+	f := newFoo()
+
+	for i := 0 ; i < 3 ; i += 1 {
+		if f.Id, er = Insert(h, "foo", "foo_id", f) ; er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("Repeated Insert - expected 1 cached statement, got %d", cache.Len())
+	}
+
+	f.Num = 43
+
+	if er := Update(h, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var f2 Foo
+
+	if er := Get(h, "foo", "foo_id", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2.Num != 43 {
+		t.Errorf("Get through the cache - Num mismatch %d != 43", f2.Num)
+	}
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected eviction down to 2 cached statements, got %d", cache.Len())
+	}
+
+	if er := cache.Close() ; er != nil {
+		t.Fatal(er)
+	}
+
+	if cache.Len() != 0 {
+		t.Errorf("Close - %d statements still cached", cache.Len())
+	}
+
+	/* A closed cache still works, it just stops preparing */
+	if _, er := Delete(h, "foo", "foo_id", f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if cache.Len() != 0 {
+		t.Errorf("Closed cache - %d statements cached", cache.Len())
+	}
+
+	/* Caches can also be bound to a transaction */
+	tx, er := db.Begin()
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	txCache := NewStmtCache(tx, 8)
+
+	if _, er := Insert(txCache, "foo", "foo_id", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := txCache.Close() ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := tx.Commit() ; er != nil {
+		t.Fatal(er)
+	}
+
+	if n := countFoos(t, db) ; n != 3 {
+		t.Errorf("Expected 3 foos, got %d", n)
+	}
+}
+
+func TestStmtCacheEvictOpenRows(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	tx, er := db.Begin()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer tx.Rollback()
+
+	for i := 0 ; i < 5 ; i += 1 {
+		if _, er := Insert(tx, "foo", "foo_id", newFoo()) ; er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	/* Closing a Tx's statement would cut its rows short, so queries aren't cached there */
+	cache := NewStmtCache(tx, 1)
+
+	rows, er := cache.Query("SELECT foo_id FROM foo")
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer rows.Close()
+
+	/* Takes the cache's only slot */
+	if _, er := cache.Exec("UPDATE foo SET foo_num = foo_num + 1 WHERE foo_id = -1") ; er != nil {
+		t.Fatal(er)
+	}
+
+	n := 0
+
+	for rows.Next() {
+		n += 1
+	}
+
+	if er := rows.Err() ; er != nil {
+		t.Fatal(er)
+	}
+
+	if n != 5 {
+		t.Errorf("Read %d of 5 rows after evicting their statement", n)
+	}
+
+	rows.Close()
+
+	if er := cache.Close() ; er != nil {
+		t.Fatal(er)
+	}
+}
+
+/* countingConnector opens connections whose driver statements are counted in open. */
+type countingConnector struct {
+	driver driver.Driver
+	open *int64
+}
+
+func (c countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, er := c.driver.Open(":memory:")
+	if er != nil {
+		return nil, er
+	}
+
+	return countingConn{conn, c.open}, nil
+}
+
+func (c countingConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type countingConn struct {
+	driver.Conn
+	open *int64
+}
+
+func (c countingConn) Prepare(q string) (driver.Stmt, error) {
+	stmt, er := c.Conn.Prepare(q)
+	if er != nil {
+		return nil, er
+	}
+
+	atomic.AddInt64(c.open, 1)
+	return countingStmt{stmt, c.open}, nil
+}
+
+type countingStmt struct {
+	driver.Stmt
+	open *int64
+}
+
+func (s countingStmt) Close() error {
+	atomic.AddInt64(s.open, -1)
+	return s.Stmt.Close()
+}
+
+func TestStmtCacheTxBounded(t *testing.T) {
+	sqlite, er := sql.Open("sqlite3", ":memory:")
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer sqlite.Close()
+
+	var open int64
+
+	db := sql.OpenDB(countingConnector{sqlite.Driver(), &open})
+	defer db.Close()
+
+	tx, er := db.Begin()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer tx.Rollback()
+
+	if _, er := tx.Exec("CREATE TABLE foo (foo_num INTEGER)") ; er != nil {
+		t.Fatal(er)
+	}
+
+	cache := NewStmtCache(tx, 2)
+
+	for i := 0 ; i < 200 ; i += 1 {
+		rows, er := cache.Query(fmt.Sprintf("SELECT foo_num + %d FROM foo", i))
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		rows.Close()
+
+		if _, er := cache.Exec(fmt.Sprintf("UPDATE foo SET foo_num = %d", i)) ; er != nil {
+			t.Fatal(er)
+		}
+	}
+
+	if n := atomic.LoadInt64(&open) ; n > 2 || cache.Len() != 2 {
+		t.Errorf("Expected at most 2 open statements, got %d (%d cached)", n, cache.Len())
+	}
+
+	if er := cache.Close() ; er != nil {
+		t.Fatal(er)
+	}
+
+	if n := atomic.LoadInt64(&open) ; n != 0 {
+		t.Errorf("Close - %d statements still open", n)
+	}
+}
@@ -0,0 +1,93 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+/*
+ScanStrict is the same as Scan, but returns a *ScanError (without scanning
+anything) unless every column of the result set is mapped to exactly one
+tagged field and vice versa. This catches, for example, a renamed column that
+would otherwise leave a field permanently zero.
+*/
+func ScanStrict(rows *sql.Rows, args ...interface{}) error {
+	cfg := defaultScanConfig()
+	cfg.strict = true
+
+	return scan(context.Background(), rows, cfg, args...)
+}
+
+/*
+ScanError is returned by strict scans (see ScanStrict and Handle.StrictScan)
+when the result set doesn't line up with the destination fields.
+*/
+type ScanError struct {
+	/* UnmappedColumns lists result columns that no tagged field maps to. */
+	UnmappedColumns []string
+
+	/* UnfilledFields lists tagged fields (as "Type.Field") that no column maps to. */
+	UnfilledFields []string
+
+	/* DuplicateColumns lists column names that appear more than once in the result set. */
+	DuplicateColumns []string
+}
+
+func (e *ScanError) Error() string {
+	problems := []string{}
+
+	if len(e.UnmappedColumns) > 0 {
+		problems = append(problems, "unmapped columns " + strings.Join(e.UnmappedColumns, ", "))
+	}
+
+	if len(e.UnfilledFields) > 0 {
+		problems = append(problems, "unfilled fields " + strings.Join(e.UnfilledFields, ", "))
+	}
+
+	if len(e.DuplicateColumns) > 0 {
+		problems = append(problems, "duplicate columns " + strings.Join(e.DuplicateColumns, ", "))
+	}
+
+	return fmt.Sprintf("strict scan failed: %s", strings.Join(problems, "; "))
+}
+
+/* scannedField records a field a strict scan expects a column for. */
+type scannedField struct {
+	name string
+	col string
+}
+
+/*
+checkColumns compares the result columns cols with the columns mapped to by
+fields (and collected in writeBackMap), returning a *ScanError if they differ.
+*/
+func checkColumns(cols []string, writeBackMap map[string]interface{}, fields []scannedField) error {
+	e := &ScanError{}
+	seen := make(map[string]int)
+
+	for _, col := range cols {
+		seen[col] += 1
+
+		if seen[col] == 2 {
+			e.DuplicateColumns = append(e.DuplicateColumns, col)
+		}
+
+		if _, ok := writeBackMap[col] ; !ok && seen[col] == 1 {
+			e.UnmappedColumns = append(e.UnmappedColumns, col)
+		}
+	}
+
+	for _, field := range fields {
+		if seen[field.col] == 0 {
+			e.UnfilledFields = append(e.UnfilledFields, fmt.Sprintf("%s (%s)", field.name, field.col))
+		}
+	}
+
+	if len(e.UnmappedColumns) == 0 && len(e.UnfilledFields) == 0 && len(e.DuplicateColumns) == 0 {
+		return nil
+	}
+
+	return e
+}
@@ -0,0 +1,95 @@
+package crud
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func strictScan(t *testing.T, db DbIsh, scan func(DbIsh, string) error, q string) *ScanError {
+	er := scan(db, q)
+	if er == nil {
+		return nil
+	}
+
+	var scanEr *ScanError
+
+	if !errors.As(er, &scanEr) {
+		t.Fatalf("%s: not a *ScanError: %s", q, er)
+	}
+
+	return scanEr
+}
+
+func TestScanStrict(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	if _, er := Insert(db, "foo", "foo_id", newFoo()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	scanOne := func(db DbIsh, q string) error {
+		rows, er := db.Query(q)
+		if er != nil {
+			t.Fatal(er)
+		}
+		defer rows.Close()
+
+		if !rows.Next() {
+			t.Fatalf("%s: no rows", q)
+		}
+
+		var f Foo
+		return ScanStrict(rows, &f)
+	}
+
+	if er := strictScan(t, db, scanOne, "SELECT * FROM foo") ; er != nil {
+		t.Errorf("Matching columns: %s", er)
+	}
+
+	scanEr := strictScan(t, db, scanOne, "SELECT foo_id, foo_num AS foo_number, foo_str, foo_time, foo_str FROM foo")
+
+	if scanEr == nil {
+		t.Fatal("Mismatched columns weren't reported")
+	}
+
+	if !reflect.DeepEqual(scanEr.UnmappedColumns, []string{"foo_number"}) {
+		t.Errorf("UnmappedColumns: %#v", scanEr.UnmappedColumns)
+	}
+
+	if !reflect.DeepEqual(scanEr.UnfilledFields, []string{"Foo.Num (foo_num)"}) {
+		t.Errorf("UnfilledFields: %#v", scanEr.UnfilledFields)
+	}
+
+	if !reflect.DeepEqual(scanEr.DuplicateColumns, []string{"foo_str"}) {
+		t.Errorf("DuplicateColumns: %#v", scanEr.DuplicateColumns)
+	}
+
+	/* Handles can be made strict for everything that scans through them */
+	h := NewHandle(db, SQLite)
+	h.StrictScan = true
+
+	scanAll := func(db DbIsh, q string) error {
+		rows, er := db.Query(q)
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		foos := []Foo{}
+		return h.ScanAll(rows, &foos)
+	}
+
+	if er := strictScan(t, h, scanAll, "SELECT * FROM foo") ; er != nil {
+		t.Errorf("Matching columns: %s", er)
+	}
+
+	scanEr = strictScan(t, h, scanAll, "SELECT foo_id, foo_num FROM foo")
+
+	if scanEr == nil || len(scanEr.UnfilledFields) != 2 {
+		t.Errorf("Handle.StrictScan - unfilled fields not reported: %v", scanEr)
+	}
+}
@@ -0,0 +1,185 @@
+package crud
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+/*
+Table is a type-safe repository for a tagged struct type T stored in a single
+table. It wraps the reflection-based functions, but the compiler checks that
+only *T's are handed to it:
+
+	users := crud.NewTable[User](db, "")
+	u, er := users.Get(ctx, id)
+
+Records are identified by T's "pk" fields. Table works with anything that is a
+DbIsh, including a *sql.Tx (see Bind) and a Handle (whose Dialect is used).
+*/
+type Table[T any] struct {
+	h *Handle
+	name string
+}
+
+/*
+NewTable returns a Table for T backed by db. If name is empty, the table name
+declared by T (see InsertObj) is used. Problems with T, such as it not being a
+struct, are reported by the first method called.
+*/
+func NewTable[T any](db DbIsh, name string) *Table[T] {
+	return &Table[T]{
+		h: handleOf(db),
+		name: name,
+	}
+}
+
+/* Bind returns a copy of the Table that talks to db (e.g. a *sql.Tx) instead. */
+func (t *Table[T]) Bind(db DbIsh) *Table[T] {
+	cp := *t
+	cp.h = t.h.Bind(db)
+	return &cp
+}
+
+/* Name returns the name of the underlying table. */
+func (t *Table[T]) Name() (string, error) {
+	_, name, er := t.meta()
+	return name, opError(er, "", t.name, "")
+}
+
+/* Insert creates a record for obj. A generated integer key is written back into obj. */
+func (t *Table[T]) Insert(ctx context.Context, obj *T) error {
+	tm, name, er := t.meta()
+	if er != nil {
+		return opError(er, "insert", t.name, "")
+	}
+
+	return insertObj(ctx, t.h, tm, name, obj)
+}
+
+/* Update syncs obj with its existing record. */
+func (t *Table[T]) Update(ctx context.Context, obj *T) error {
+	_, name, er := t.keyMeta()
+	if er != nil {
+		return opError(er, "update", t.name, "")
+	}
+
+	return UpdateContext(ctx, t.h, name, "", obj)
+}
+
+/* Delete removes obj's record and returns the number of rows affected. */
+func (t *Table[T]) Delete(ctx context.Context, obj *T) (int64, error) {
+	_, name, er := t.keyMeta()
+	if er != nil {
+		return 0, opError(er, "delete", t.name, "")
+	}
+
+	return DeleteContext(ctx, t.h, name, "", obj)
+}
+
+/*
+Get loads the record with the given primary key (a Key for composite keys).
+ErrNotFound is returned if there is none.
+*/
+func (t *Table[T]) Get(ctx context.Context, id interface{}) (T, error) {
+	var obj T
+
+	_, name, er := t.keyMeta()
+	if er != nil {
+		return obj, opError(er, "get", t.name, "")
+	}
+
+	er = GetContext(ctx, t.h, name, "", id, &obj)
+	return obj, er
+}
+
+/*
+List returns every record matching where, which is used verbatim as the SQL
+following WHERE (and may use the Dialect's placeholders for args). An empty
+where lists the whole table.
+*/
+func (t *Table[T]) List(ctx context.Context, where string, args ...interface{}) ([]T, error) {
+	rows, er := t.query(ctx, where, args...)
+	if er != nil {
+		return nil, er
+	}
+
+	objs := []T{}
+
+	if er := scanAll(ctx, rows, t.h.scanConfig(), &objs) ; er != nil {
+		return nil, er
+	}
+
+	return objs, nil
+}
+
+/*
+Iter is the same as List, but yields the records one at a time instead of
+buffering them. Iteration stops at the first error, which is yielded along with
+a zero T.
+*/
+func (t *Table[T]) Iter(ctx context.Context, where string, args ...interface{}) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		rows, er := t.query(ctx, where, args...)
+		if er != nil {
+			var zero T
+			yield(zero, er)
+			return
+		}
+
+		rowsContext[T](ctx, rows, t.h.scanConfig())(yield)
+	}
+}
+
+/* query runs the SELECT shared by List and Iter. */
+func (t *Table[T]) query(ctx context.Context, where string, args ...interface{}) (*sql.Rows, error) {
+	tm, name, er := t.meta()
+	if er != nil {
+		return nil, opError(er, "select", t.name, "")
+	}
+
+	d := t.h.dialect()
+	q := fmt.Sprintf("SELECT %s FROM %s", selectColumns(d, tm), quoteName(d, name))
+
+	if where != "" {
+		q += " WHERE " + where
+	}
+
+	rows, er := t.h.QueryContext(ctx, q, args...)
+	return rows, opError(er, "select", name, q)
+}
+
+/* meta returns T's metadata along with the name of the table. */
+func (t *Table[T]) meta() (*typeMeta, string, error) {
+	/* sqlToGoFields would look through a pointer T, but *T must be a pointer to a struct */
+	if ty := reflect.TypeOf((*T)(nil)).Elem() ; ty.Kind() != reflect.Struct {
+		return nil, "", fmt.Errorf("%s is %w", ty, ErrNotStruct)
+	}
+
+	tm, er := sqlToGoFields(reflect.TypeOf((*T)(nil)))
+	if er != nil {
+		return nil, "", er
+	}
+
+	if t.name != "" {
+		return tm, t.name, nil
+	}
+
+	return objMeta(tm.Type)
+}
+
+/* keyMeta is meta for operations that need at least one "pk" field. */
+func (t *Table[T]) keyMeta() (*typeMeta, string, error) {
+	tm, name, er := t.meta()
+	if er != nil {
+		return nil, "", er
+	}
+
+	if len(tm.PKs) == 0 {
+		return nil, "", fmt.Errorf("%s has %w", tm.Type, ErrNoKey)
+	}
+
+	return tm, name, nil
+}
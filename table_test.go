@@ -0,0 +1,128 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTable(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	foos := NewTable[ObjFoo](db, "")
+
+	if name, er := foos.Name() ; er != nil || name != "foo" {
+		t.Errorf("Unexpected table name: %s, %v", name, er)
+	}
+
+	for i := int64(1) ; i <= 3 ; i += 1 {
+		f := ObjFoo{Num: i, Str: "table", Time: time.Unix(1338, 0).UTC()}
+
+		if er := foos.Insert(ctx, &f) ; er != nil {
+			t.Fatal(er)
+		}
+
+		if f.Id == 0 {
+			t.Fatalf("Table.Insert did not write back the id")
+		}
+	}
+
+	f, er := foos.Get(ctx, int64(2))
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Num != 2 {
+		t.Errorf("Table.Get mismatch: %#v", f)
+	}
+
+	f.Str = "updated"
+
+	if er := foos.Update(ctx, &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	list, er := foos.List(ctx, "foo_num >= $1 ORDER BY foo_num", 2)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if len(list) != 2 || list[0].Str != "updated" || list[1].Num != 3 {
+		t.Errorf("Table.List mismatch: %#v", list)
+	}
+
+	seen := 0
+
+	for f, er := range foos.Iter(ctx, "") {
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		seen += 1
+
+		if f.Num == 1 {
+			break
+		}
+	}
+
+	if seen != 1 {
+		t.Errorf("Table.Iter did not stop early: saw %d", seen)
+	}
+
+	if n, er := foos.Delete(ctx, &f) ; er != nil || n != 1 {
+		t.Errorf("Table.Delete failed: %d, %v", n, er)
+	}
+
+	if _, er := foos.Get(ctx, f.Id) ; !errors.Is(er, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", er)
+	}
+}
+
+func TestTableTx(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	foos := NewTable[Foo](NewHandle(db, SQLite), "foo")
+
+	if _, er := foos.Get(ctx, int64(1)) ; er == nil {
+		t.Errorf("Expected Table.Get to error on a type without pk fields")
+	}
+
+	tx, er := db.Begin()
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	f := newFoo()
+
+	if er := foos.Bind(tx).Insert(ctx, &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := tx.Rollback() ; er != nil {
+		t.Fatal(er)
+	}
+
+	if list, er := foos.List(ctx, "") ; er != nil || len(list) != 0 {
+		t.Errorf("Expected rolled back insert to be gone: %#v, %v", list, er)
+	}
+
+	if _, er := NewTable[int](db, "ints").List(ctx, "") ; !errors.Is(er, ErrNotStruct) {
+		t.Errorf("Expected Table of a non-struct to error, got %v", er)
+	}
+
+	/* A Table of pointers would otherwise hand **T's around */
+	if _, er := NewTable[*ObjFoo](db, "").Get(ctx, int64(1)) ; !errors.Is(er, ErrNotStruct) {
+		t.Errorf("Expected Table of a pointer to error, got %v", er)
+	}
+}
@@ -0,0 +1,221 @@
+package crud
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+/*
+Track takes a Snapshot of obj's tagged fields, typically right after it was
+read with Get or Scan, so that Save can later write back only the columns that
+changed since:
+
+	crud.Get(db, "foo", "foo_id", id, &f)
+	snap, er := crud.Track(&f)
+
+	f.Num += 1
+	snap.Save(db) // UPDATE "foo" SET "foo_num" = $1 WHERE "foo_id" = $2
+
+obj must be a pointer to a tagged struct, which the Snapshot compares against
+its recorded values. Nothing is kept anywhere else, so a Snapshot (and obj)
+can be dropped at any time.
+*/
+func Track(obj interface{}) (*Snapshot, error) {
+	val := reflect.ValueOf(obj)
+
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil, opError(fmt.Errorf("%T is %w, cannot be tracked", obj, ErrNotPointer), "track", "", "")
+	}
+
+	tm, er := sqlToGoFields(val.Type())
+	if er != nil {
+		return nil, opError(er, "track", "", "")
+	}
+
+	values, er := snapshot(val.Elem(), tm)
+	if er != nil {
+		return nil, opError(er, "track", "", "")
+	}
+
+	return &Snapshot{obj: obj, tm: tm, values: values}, nil
+}
+
+/* Snapshot records the values of a tracked object's fields; see Track. */
+type Snapshot struct {
+	obj interface{}
+	tm *typeMeta
+
+	/* values holds the snapshotted value of each of tm.Fields. */
+	values []interface{}
+}
+
+/*
+Change describes a column whose value differs from its tracked snapshot. Old
+and New are the values as they are (or would be) written to the database, so
+times tagged "unix" are integers, nil pointers are nil, and so on.
+*/
+type Change struct {
+	Column string
+	Field string
+	Old interface{}
+	New interface{}
+}
+
+/*
+Changes returns the columns of the tracked object that changed since the
+snapshot was taken, in field declaration order, e.g. for audit logging. Fields
+tagged "readonly" or "insertonly" are ignored, since Save won't write them. It
+returns an empty slice if nothing changed.
+*/
+func (s *Snapshot) Changes() ([]Change, error) {
+	changes, er := diff(reflect.ValueOf(s.obj).Elem(), s.tm, s.values)
+	return changes, opError(er, "track", "", "")
+}
+
+/*
+Save writes the columns of the tracked object that changed since the snapshot
+was taken to its record (using UpdateColumns), and takes a new snapshot. No
+statement is run at all if nothing changed.
+
+The table name and key come from the object's type, as for UpdateObj. Key
+columns can't be changed this way.
+*/
+func (s *Snapshot) Save(db DbIsh) error {
+	return s.SaveContext(context.Background(), handleOf(db))
+}
+
+/* SaveContext is the same as Save, but passes ctx through to the database. */
+func (s *Snapshot) SaveContext(ctx context.Context, db DbIshContext) error {
+	_, table, er := objKeyMeta(s.tm.Type)
+	if er != nil {
+		return opError(er, "update", "", "")
+	}
+
+	val := reflect.ValueOf(s.obj).Elem()
+
+	changes, er := diff(val, s.tm, s.values)
+	if er != nil {
+		return opError(er, "update", table, "")
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	cols := make([]string, len(changes))
+
+	for i, change := range changes {
+		cols[i] = change.Column
+	}
+
+	if er := UpdateColumnsContext(ctx, db, table, "", s.obj, cols...) ; er != nil {
+		return er
+	}
+
+	values, er := snapshot(val, s.tm)
+	if er != nil {
+		return opError(er, "update", table, "")
+	}
+
+	s.values = values
+	return nil
+}
+
+/* snapshot returns the values of val's tagged fields, as they would be written. */
+func snapshot(val reflect.Value, tm *typeMeta) ([]interface{}, error) {
+	snap := make([]interface{}, len(tm.Fields))
+
+	for i := range tm.Fields {
+		meta := &tm.Fields[i]
+
+		v, er := snapshotValue(fieldValue(val, meta))
+		if er != nil {
+			return nil, fieldError(meta, er)
+		}
+
+		snap[i] = v
+	}
+
+	return snap, nil
+}
+
+/* diff compares val's tagged fields with snap, as taken by snapshot. */
+func diff(val reflect.Value, tm *typeMeta, snap []interface{}) ([]Change, error) {
+	cur, er := snapshot(val, tm)
+	if er != nil {
+		return nil, er
+	}
+
+	changes := []Change{}
+
+	for i := range tm.Fields {
+		meta := &tm.Fields[i]
+
+		if meta.updated() && !sameValue(snap[i], cur[i]) {
+			changes = append(changes, Change{meta.SqlName, meta.GoName, snap[i], cur[i]})
+		}
+	}
+
+	return changes, nil
+}
+
+/*
+snapshotValue copies v (as returned by fieldValue), so that later changes to
+the field can't reach it: Valuers are resolved, pointers are dereferenced and
+byte slices are copied.
+
+As in database/sql, a nil pointer to a type with a value receiver Value method
+is NULL rather than a call through a nil pointer.
+*/
+func snapshotValue(v interface{}) (interface{}, error) {
+	if val := reflect.ValueOf(v) ; val.Kind() == reflect.Ptr && val.IsNil() && val.Type().Elem().Implements(valuerType) {
+		return nil, nil
+	}
+
+	if valuer, ok := v.(driver.Valuer) ; ok {
+		var er error
+
+		if v, er = valuer.Value() ; er != nil {
+			return nil, er
+		}
+	}
+
+	val := reflect.ValueOf(v)
+
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, nil
+		}
+
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		return nil, nil
+	}
+
+	if val.Kind() == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8 {
+		if val.IsNil() {
+			return nil, nil
+		}
+
+		bytes := make([]byte, val.Len())
+		reflect.Copy(reflect.ValueOf(bytes), val)
+		return bytes, nil
+	}
+
+	return val.Interface(), nil
+}
+
+/* sameValue reports whether two snapshotted values are equal; times are compared with Equal. */
+func sameValue(a, b interface{}) bool {
+	if at, ok := a.(time.Time) ; ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,157 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type TrackedFoo struct {
+	_ struct{} `crud:"table:foo"`
+	Id int64 `crud:"foo_id,pk"`
+	Num int64 `crud:"foo_num"`
+	Str string `crud:"foo_str"`
+	Time time.Time `crud:"foo_time"`
+}
+
+type TrackedBytes struct {
+	Bytes []byte `crud:"bytes"`
+	Ptr *int64 `crud:"ptr"`
+
+	/* Csv has a value receiver Value, which mustn't be called through nil */
+	Csv *Csv `crud:"csv"`
+}
+
+func TestTrack(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	rec := &recordDb{DbIshContext: db}
+
+	f := TrackedFoo{Num: 42, Str: "PANIC", Time: time.Unix(1338, 0).UTC()}
+
+	if er := InsertObj(db, &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var g TrackedFoo
+
+	if er := Get(db, "foo", "foo_id", f.Id, &g) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if _, er := Track(g) ; !errors.Is(er, ErrNotPointer) {
+		t.Errorf("Track of a non-pointer: %v", er)
+	}
+
+	snap, er := Track(&g)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	/* Nothing changed, nothing is run (the time only changes location) */
+	g.Time = g.Time.Local()
+
+	if changes, er := snap.Changes() ; er != nil || len(changes) != 0 {
+		t.Errorf("Changes on unchanged object: %#v, %v", changes, er)
+	}
+
+	if er := snap.SaveContext(ctx, rec) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if len(rec.queries) != 0 {
+		t.Errorf("Save of unchanged object ran %#v", rec.queries)
+	}
+
+	g.Str = "changed"
+
+	changes, er := snap.Changes()
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if len(changes) != 1 || changes[0] != (Change{"foo_str", "Str", "PANIC", "changed"}) {
+		t.Errorf("Changes mismatch: %#v", changes)
+	}
+
+	if er := snap.SaveContext(ctx, rec) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `UPDATE "foo" SET "foo_str" = $1 WHERE "foo_id" = $2` ; len(rec.queries) != 1 || rec.queries[0] != expected {
+		t.Errorf("SQL mismatch\n%#v\n%s", rec.queries, expected)
+	}
+
+	/* Save takes a new snapshot */
+	if changes, er := snap.Changes() ; er != nil || len(changes) != 0 {
+		t.Errorf("Changes after Save: %#v, %v", changes, er)
+	}
+
+	if er := Reload(db, "foo", "foo_id", &f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f.Str != "changed" || f.Num != 42 {
+		t.Errorf("Save - unexpected record %#v", f)
+	}
+
+	g.Id += 1
+
+	if er := snap.Save(db) ; er == nil {
+		t.Errorf("Save of a changed key succeeded")
+	}
+
+	/* Types without a table name can be tracked, but not saved */
+	b := TrackedBytes{}
+
+	if snap, er = Track(&b) ; er != nil {
+		t.Fatal(er)
+	}
+
+	b.Ptr = new(int64)
+
+	if er := snap.Save(db) ; !errors.Is(er, ErrNoTable) {
+		t.Errorf("Save without a table name: %v", er)
+	}
+}
+
+func TestTrackSnapshotCopies(t *testing.T) {
+	n := int64(1)
+	b := TrackedBytes{Bytes: []byte("abc"), Ptr: &n}
+
+	snap, er := Track(&b)
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	/* Changes made through the field's pointer and backing array are seen */
+	b.Bytes[0] = 'x'
+	n = 2
+
+	changes, er := snap.Changes()
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if len(changes) != 2 || string(changes[0].Old.([]byte)) != "abc" || changes[1].Old != int64(1) || changes[1].New != int64(2) {
+		t.Errorf("Changes mismatch: %#v", changes)
+	}
+
+	b.Ptr = nil
+
+	if changes, _ := snap.Changes() ; len(changes) != 2 || changes[1].New != nil {
+		t.Errorf("Changes mismatch: %#v", changes)
+	}
+
+	b.Csv = &Csv{"a", "b"}
+
+	if changes, _ := snap.Changes() ; len(changes) != 3 || changes[2].Old != nil || changes[2].New != "a,b" {
+		t.Errorf("Changes mismatch: %#v", changes)
+	}
+}
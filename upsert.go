@@ -0,0 +1,141 @@
+package crud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+/* UpsertOption adjusts what Upsert does when the INSERT hits a conflict. */
+type UpsertOption func(*upsertConfig)
+
+type upsertConfig struct {
+	update []string
+	doNothing bool
+}
+
+/*
+OnConflictUpdate limits the columns overwritten on conflict to cols. By default
+every inserted column that isn't a conflict column (or tagged "insertonly") is
+overwritten. Columns in cols are inserted even when they are zero and tagged
+"omitempty", since the value they are overwritten with is the inserted one. The version column (see Update) can't be: Upsert can neither check
+nor bump it, and overwriting it with obj's would defeat the locking.
+*/
+func OnConflictUpdate(cols ...string) UpsertOption {
+	return func(cfg *upsertConfig) {
+		cfg.update = cols
+	}
+}
+
+/* OnConflictDoNothing leaves the existing row untouched on conflict. */
+func OnConflictDoNothing() UpsertOption {
+	return func(cfg *upsertConfig) {
+		cfg.doNothing = true
+	}
+}
+
+/*
+Upsert inserts a tagged object, or updates the existing record if the INSERT
+would violate the unique constraint over conflictColumns.
+
+The columns are inserted as with Insert (and an empty sqlIdFieldName), and
+overwritten on conflict unless they are tagged "insertonly" or "version".
+The conflict clause is generated by the Handle's Dialect: "ON CONFLICT ... DO
+UPDATE" on SQLite and Postgres, "ON DUPLICATE KEY UPDATE" on MySQL. MSSQL is
+not supported.
+*/
+func Upsert(db DbIsh, table string, conflictColumns []string, obj interface{}, opts ...UpsertOption) error {
+	return UpsertContext(context.Background(), handleOf(db), table, conflictColumns, obj, opts...)
+}
+
+/* UpsertContext is the same as Upsert, but passes ctx through to the database. */
+func UpsertContext(ctx context.Context, db DbIshContext, table string, conflictColumns []string, obj interface{}, opts ...UpsertOption) error {
+	h := handleOfContext(db)
+
+	q, args, er := buildUpsert(h.dialect(), h.nullPolicy(), table, conflictColumns, obj, opts...)
+	if er != nil {
+		return opError(er, "upsert", table, "")
+	}
+
+	_, er = h.ExecContext(ctx, q, args...)
+	return opError(er, "upsert", table, q)
+}
+
+/* buildUpsert generates the upsert statement (and its arguments) for obj. */
+func buildUpsert(d Dialect, nulls NullPolicy, table string, conflictColumns []string, obj interface{}, opts ...UpsertOption) (string, []interface{}, error) {
+	cfg := upsertConfig{}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	val := indirectV(reflect.ValueOf(obj))
+
+	tm, er := sqlToGoFields(val.Type())
+	if er != nil {
+		return "", nil, er
+	}
+
+	sqlFields, newValues := insertColumns(val, tm, nulls, true)
+	update := []string{}
+
+	if cfg.doNothing {
+		/* leave update empty */
+
+	} else if cfg.update != nil {
+		for _, col := range cfg.update {
+			meta, ok := tm.lookup(col)
+			if !ok {
+				return "", nil, &Error{Column: col, Err: fmt.Errorf("%w of %s", ErrUnknownColumn, tm.Type)}
+			}
+
+			if !meta.updated() {
+				return "", nil, fieldError(meta, fmt.Errorf("readonly and insertonly columns can't be updated"))
+			}
+
+			if meta == tm.version {
+				return "", nil, fieldError(meta, fmt.Errorf("the version column can't be overwritten by Upsert"))
+			}
+
+			/* Columns asked for by name are written even if omitempty left them out, so they aren't overwritten with DEFAULT */
+			if !hasColumn(sqlFields, col) {
+				sqlFields = append(sqlFields, col)
+				newValues = append(newValues, columnValue(val, meta, nulls))
+			}
+		}
+
+		update = cfg.update
+
+	} else {
+		conflicts := make(map[string]bool)
+
+		for _, col := range conflictColumns {
+			conflicts[col] = true
+		}
+
+		for _, col := range sqlFields {
+			if meta, _ := tm.lookup(col) ; !conflicts[col] && meta.updated() && meta != tm.version {
+				update = append(update, col)
+			}
+		}
+	}
+
+	clause, er := d.OnConflict(conflictColumns, update)
+	if er != nil {
+		return "", nil, er
+	}
+
+	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) %s", quoteName(d, table), quoteColumns(d, sqlFields), placeholders(d, 1, len(newValues)), clause)
+	return q, newValues, nil
+}
+
+/* hasColumn reports whether col is one of cols. */
+func hasColumn(cols []string, col string) bool {
+	for _, c := range cols {
+		if c == col {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package crud
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpsertGoldenSQL(t *testing.T) {
+	b := Bar{
+		Id: 7,
+		Name: "seven",
+	}
+
+	expected := map[Dialect][]string{
+		SQLite: {
+			`INSERT INTO "bar" ("bar_id", "bar_name") VALUES ($1, $2) ON CONFLICT ("bar_id") DO UPDATE SET "bar_name" = excluded."bar_name"`,
+			`INSERT INTO "bar" ("bar_id", "bar_name") VALUES ($1, $2) ON CONFLICT ("bar_id") DO NOTHING`,
+		},
+		Postgres: {
+			`INSERT INTO "bar" ("bar_id", "bar_name") VALUES ($1, $2) ON CONFLICT ("bar_id") DO UPDATE SET "bar_name" = excluded."bar_name"`,
+			`INSERT INTO "bar" ("bar_id", "bar_name") VALUES ($1, $2) ON CONFLICT ("bar_id") DO NOTHING`,
+		},
+		MySQL: {
+			"INSERT INTO `bar` (`bar_id`, `bar_name`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `bar_name` = VALUES(`bar_name`)",
+			"INSERT INTO `bar` (`bar_id`, `bar_name`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `bar_id` = `bar_id`",
+		},
+	}
+
+	for d, stmts := range expected {
+		q, args, er := buildUpsert(d, NullStrict, "bar", []string{"bar_id"}, b)
+		if er != nil {
+			t.Fatal(er)
+		}
+
+		if q != stmts[0] || len(args) != 2 {
+			t.Errorf("upsert mismatch:\ne: %s\na: %s", stmts[0], q)
+		}
+
+		if q, _, er = buildUpsert(d, NullStrict, "bar", []string{"bar_id"}, b, OnConflictDoNothing()) ; er != nil {
+			t.Fatal(er)
+
+		} else if q != stmts[1] {
+			t.Errorf("upsert (do nothing) mismatch:\ne: %s\na: %s", stmts[1], q)
+		}
+	}
+
+	if _, _, er := buildUpsert(MSSQL, NullStrict, "bar", []string{"bar_id"}, b) ; !errors.Is(er, errors.ErrUnsupported) {
+		t.Errorf("Expected MSSQL upsert to be unsupported, got %v", er)
+	}
+
+	if _, _, er := buildUpsert(SQLite, NullStrict, "bar", []string{"bar_id"}, b, OnConflictUpdate("nope")) ; er == nil {
+		t.Errorf("Expected Upsert to error on an unknown update column")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	f := newFoo()
+	f.Id = 10
+
+	if er := Upsert(db, "foo", []string{"foo_id"}, f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	f.Num = 11
+	f.Str = "updated"
+
+	if er := Upsert(db, "foo", []string{"foo_id"}, f, OnConflictUpdate("foo_num")) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var f2 Foo
+
+	if er := Get(db, "foo", "foo_id", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2.Num != 11 || f2.Str != "PANIC" {
+		t.Errorf("Upsert updated the wrong columns: %#v", f2)
+	}
+
+	f.Num = 12
+
+	if er := Upsert(db, "foo", []string{"foo_id"}, f, OnConflictDoNothing()) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Get(db, "foo", "foo_id", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2.Num != 11 {
+		t.Errorf("OnConflictDoNothing modified the row: %#v", f2)
+	}
+
+	if c := countFoos(t, db) ; c != 1 {
+		t.Errorf("Expected 1 foo, got %d", c)
+	}
+}
+
+func TestUpsertOmitEmpty(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	_, er = db.Exec(`
+		CREATE TABLE dfoo
+			( d_id INTEGER PRIMARY KEY
+			, d_num INTEGER NOT NULL DEFAULT 7
+			, d_str VARCHAR(32) NOT NULL DEFAULT 'default'
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	f := DefaultFoo{Id: 1, Num: 3, Str: "str"}
+
+	if er := Upsert(db, "dfoo", []string{"d_id"}, f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	/* Zeroing an omitempty column on conflict must write the zero, not its DEFAULT */
+	f.Num = 0
+
+	q, args, er := buildUpsert(SQLite, NullStrict, "dfoo", []string{"d_id"}, f, OnConflictUpdate("d_num"))
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `INSERT INTO "dfoo" ("d_id", "d_str", "d_num") VALUES ($1, $2, $3) ON CONFLICT ("d_id") DO UPDATE SET "d_num" = excluded."d_num"` ; q != expected || len(args) != 3 {
+		t.Errorf("upsert mismatch:\ne: %s\na: %s (%#v)", expected, q, args)
+	}
+
+	if er := Upsert(db, "dfoo", []string{"d_id"}, f, OnConflictUpdate("d_num")) ; er != nil {
+		t.Fatal(er)
+	}
+
+	var f2 DefaultFoo
+
+	if er := Get(db, "dfoo", "d_id", f.Id, &f2) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if f2 != f {
+		t.Errorf("OnConflictUpdate of an omitempty column mismatch: %#v != %#v", f2, f)
+	}
+}
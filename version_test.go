@@ -0,0 +1,149 @@
+package crud
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type VersionedFoo struct {
+	Id int64 `crud:"v_id,pk"`
+	Num int64 `crud:"v_num"`
+	Version int32 `crud:"v_version,version"`
+}
+
+func createVersionTable(t *testing.T, db DbIsh) {
+	_, er := db.Exec(`
+		CREATE TABLE vfoo
+			( v_id INTEGER PRIMARY KEY
+			, v_num INTEGER NOT NULL
+			, v_version INTEGER NOT NULL
+			)
+	`)
+
+	if er != nil {
+		t.Fatal(er)
+	}
+}
+
+func TestVersionedUpdateSQL(t *testing.T) {
+	q, args, er := buildUpdate(Postgres, NullStrict, "vfoo", "", &VersionedFoo{Id: 1, Num: 2, Version: 3})
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	expected := `UPDATE "vfoo" SET "v_num" = $1, "v_version" = $2 WHERE "v_id" = $3 AND "v_version" = $4`
+
+	if q != expected {
+		t.Errorf("SQL mismatch\n%s\n%s", q, expected)
+	}
+
+	if !reflect.DeepEqual(args, []interface{}{int64(2), int64(4), int64(1), int64(3)}) {
+		t.Errorf("Args mismatch: %#v", args)
+	}
+
+	if _, _, er := buildUpdate(Postgres, NullStrict, "vfoo", "", VersionedFoo{Id: 1}) ; !errors.Is(er, ErrNotPointer) {
+		t.Errorf("Expected ErrNotPointer for a versioned value, got %v", er)
+	}
+
+	/* Upsert leaves the version alone */
+	q, _, er = buildUpsert(Postgres, NullStrict, "vfoo", []string{"v_id"}, &VersionedFoo{Id: 1, Num: 2, Version: 3})
+	if er != nil {
+		t.Fatal(er)
+	}
+
+	if expected := `INSERT INTO "vfoo" ("v_id", "v_num", "v_version") VALUES ($1, $2, $3) ON CONFLICT ("v_id") DO UPDATE SET "v_num" = excluded."v_num"` ; q != expected {
+		t.Errorf("Upsert SQL mismatch\n%s\n%s", q, expected)
+	}
+
+	if _, _, er := buildUpsert(Postgres, NullStrict, "vfoo", []string{"v_id"}, &VersionedFoo{Id: 1}, OnConflictUpdate("v_version")) ; er == nil {
+		t.Errorf("Upsert overwriting the version accepted")
+	}
+}
+
+func TestVersionedUpdate(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	createVersionTable(t, db)
+
+	v := VersionedFoo{Num: 1}
+
+	if v.Id, er = Insert(db, "vfoo", "v_id", v) ; er != nil {
+		t.Fatal(er)
+	}
+
+	mine, theirs := v, v
+	mine.Num = 2
+
+	if er := Update(db, "vfoo", "", &mine) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if mine.Version != 1 {
+		t.Errorf("Version not bumped: %d", mine.Version)
+	}
+
+	theirs.Num = 3
+
+	if er := Update(db, "vfoo", "", &theirs) ; !errors.Is(er, ErrStaleObject) {
+		t.Errorf("Expected ErrStaleObject, got %v", er)
+	}
+
+	if theirs.Version != 0 {
+		t.Errorf("Stale version bumped: %d", theirs.Version)
+	}
+
+	var stored VersionedFoo
+
+	if er := Get(db, "vfoo", "", v.Id, &stored) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if stored != mine {
+		t.Errorf("Lost update: %#v != %#v", stored, mine)
+	}
+}
+
+func TestRequireAffected(t *testing.T) {
+	db, er := createDb()
+	if er != nil {
+		t.Fatal(er)
+	}
+	defer db.Close()
+
+	missing := newFoo()
+	missing.Id = 42
+
+	if er := Update(db, "foo", "foo_id", missing) ; er != nil {
+		t.Errorf("Update of a missing record failed without RequireAffected: %v", er)
+	}
+
+	h := NewHandle(db, SQLite)
+	h.RequireAffected = true
+
+	if er := Update(h, "foo", "foo_id", missing) ; !errors.Is(er, ErrNoRowsAffected) {
+		t.Errorf("Expected ErrNoRowsAffected, got %v", er)
+	}
+
+	f := newFoo()
+
+	if f.Id, er = Insert(h, "foo", "foo_id", f) ; er != nil {
+		t.Fatal(er)
+	}
+
+	if er := Update(h, "foo", "foo_id", f) ; er != nil {
+		t.Errorf("Update of an existing record failed: %v", er)
+	}
+
+	if n, er := UpdateAffected(db, "foo", "foo_id", f) ; er != nil || n != 1 {
+		t.Errorf("UpdateAffected of an existing record: %d, %v", n, er)
+	}
+
+	if n, er := UpdateAffected(db, "foo", "foo_id", missing) ; er != nil || n != 0 {
+		t.Errorf("UpdateAffected of a missing record: %d, %v", n, er)
+	}
+}